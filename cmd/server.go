@@ -4,16 +4,20 @@ import (
 	"bytes"
 	"context"
 	encode_box "encode-box/pkg/encode-box"
+	"encode-box/pkg/encoder"
+	"encode-box/pkg/encoder/filtergraph"
 	"encode-box/pkg/logger"
 	object_storage "encode-box/pkg/object-storage"
 	progress_broker "encode-box/pkg/progress-broker"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/dapr/go-sdk/client"
 	"github.com/joho/godotenv"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials/insecure"
 	"io"
+	"io/fs"
 	"net"
 	"net/http"
 	"os"
@@ -31,6 +35,12 @@ var (
 	broker *progress_broker.ProgressBroker
 	// Object store instance, use to retrieve/upload assets
 	objStore *object_storage.ObjectStorage
+	// Quality profile of requests not defining one
+	defaultQuality string
+	// FFmpeg filters requests can use as custom filters
+	allowedFilters []string
+	// Encoding recipes requests can use, indexed by name
+	recipes map[string]*encoder.Recipe
 )
 
 const (
@@ -39,6 +49,14 @@ const (
 	PUBSUB_NAME              = "PUBSUB_NAME"
 	PUBSUB_TOPIC_PROGRESS    = "PUBSUB_TOPIC_PROGRESS"
 	DAPR_MAX_REQUEST_SIZE_MB = "DAPR_MAX_REQUEST_SIZE_MB"
+	// Directory containing the fonts usable by text overlays
+	FONTS_DIR = "FONTS_DIR"
+	// Quality profile of requests not defining one
+	DEFAULT_QUALITY = "DEFAULT_QUALITY"
+	// Comma separated names of the FFmpeg filters requests can use as custom filters
+	ALLOWED_FILTERS = "ALLOWED_FILTERS"
+	// Directory containing the encoding recipes
+	RECIPES_DIR = "RECIPES_DIR"
 	// HTTP port for the server
 	APP_PORT = "APP_PORT"
 	// GRPC port to use to communicate with DAPR
@@ -112,6 +130,8 @@ func encodeSync(w http.ResponseWriter, req *http.Request, comp components) {
 		http.Error(w, "Unexpected error", http.StatusInternalServerError)
 		return
 	}
+	// ... along with the generated extras (poster, sprite...), already listed in the Done event
+	uploadExtras(comp.eBox, comp.objStore)
 
 	// And clean up temp files on the container filesystem
 	// Downloaded assets are already cleaned up by the encode-box itself
@@ -135,6 +155,17 @@ func encodeSync(w http.ResponseWriter, req *http.Request, comp components) {
 	_, _ = w.Write([]byte("OK"))
 }
 
+// Upload all extras generated with the output next to it, under the keys listed in the result.
+// Extras are optional, an extra that can't be uploaded is only logged
+func uploadExtras(eBox *encode_box.EncodeBox, objStore *object_storage.ObjectStorage) {
+	for kind, path := range eBox.Extras {
+		log.Infof(`Uploading "%s" on the backend object storage`, path)
+		if err := objStore.Upload(path, eBox.Result.Extras[kind]); err != nil {
+			log.Warnf(`could not upload %s "%s" : %s`, kind, path, err.Error())
+		}
+	}
+}
+
 // Health endpoint
 func healthz(w http.ResponseWriter, req *http.Request) {
 	w.WriteHeader(http.StatusOK)
@@ -189,7 +220,13 @@ func cleanUpFromObjectStore(eReq *encode_box.EncodingRequest, objStore *object_s
 			failures = append(failures, key)
 		}
 	}
-	// Image
+	// Image(s)
+	for _, key := range eReq.ImageKeys {
+		err := objStore.Delete(key)
+		if err != nil {
+			failures = append(failures, key)
+		}
+	}
 	if eReq.ImageKey != "" {
 		err := objStore.Delete(eReq.ImageKey)
 		if err != nil {
@@ -197,6 +234,14 @@ func cleanUpFromObjectStore(eReq *encode_box.EncodingRequest, objStore *object_s
 		}
 	}
 
+	// Subtitles. Intro and outro are shared between records, they are never deleted
+	if eReq.SubtitleKey != "" {
+		err := objStore.Delete(eReq.SubtitleKey)
+		if err != nil {
+			failures = append(failures, eReq.SubtitleKey)
+		}
+	}
+
 	if len(failures) > 0 {
 		return fmt.Errorf(`failed to delete "%s" from remote object storage`, strings.Join(failures, ", "))
 	}
@@ -259,7 +304,7 @@ func encode(eBox *encode_box.EncodeBox, req *encode_box.EncodingRequest, output
 				broker.SendProgress(progress_broker.EncodeInfos{
 					JobId: req.JobId,
 					State: progress_broker.Done,
-					Data:  nil,
+					Data:  eBox.Result,
 				})
 			}
 			return nil, http.StatusOK
@@ -308,6 +353,31 @@ func loadComponents() error {
 		return fmt.Errorf("cannot init dapr client : %w", err)
 	}
 	objStore = object_storage.NewObjectStorage(&ctx, *daprClient, objStoreComponent, true)
+	defaultQuality = os.Getenv(DEFAULT_QUALITY)
+	if defaultQuality != "" && !encode_box.IsQualityProfile(defaultQuality) {
+		return fmt.Errorf("unknown default quality profile \"%s\". Aborting", defaultQuality)
+	}
+	for _, name := range strings.Split(os.Getenv(ALLOWED_FILTERS), ",") {
+		if name = strings.TrimSpace(name); name == "" {
+			continue
+		}
+		if !filtergraph.IsValidFilterName(name) {
+			return fmt.Errorf("invalid allowed filter name \"%s\". Aborting", name)
+		}
+		allowedFilters = append(allowedFilters, name)
+	}
+	// Recipes are optional. Only a missing bundled directory is fine, a configured one must be valid
+	recipesDir := os.Getenv(RECIPES_DIR)
+	if recipesDir == "" {
+		recipesDir = encode_box.DefaultRecipesDir
+	}
+	recipes, err = encoder.LoadRecipes(recipesDir)
+	if err != nil {
+		if os.Getenv(RECIPES_DIR) != "" || !errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("could not load recipes : %w. Aborting", err)
+		}
+		log.Infof("No recipes directory found at %s, recipes are disabled", recipesDir)
+	}
 	// Next, load the event broker. This is optional, the server can function without it defined
 	pubSubComponent := os.Getenv(PUBSUB_NAME)
 	pubSubTopic := os.Getenv(PUBSUB_TOPIC_PROGRESS)
@@ -336,7 +406,7 @@ func main() {
 	}
 	http.HandleFunc("/encode", func(w http.ResponseWriter, req *http.Request) {
 		encodeSync(w, req, components{
-			eBox:     encode_box.NewEncodeBox(&ctx, objStore, &encode_box.EncodeBoxOptions{ObjStoreMaxRetry: 10}),
+			eBox:     encode_box.NewEncodeBox(&ctx, objStore, &encode_box.EncodeBoxOptions{ObjStoreMaxRetry: 10, FontsDir: os.Getenv(FONTS_DIR), DefaultQuality: defaultQuality, AllowedFilters: allowedFilters, Recipes: recipes}),
 			objStore: objStore,
 		})
 	})
@@ -15,6 +15,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"path/filepath"
 	"testing"
 )
 
@@ -175,8 +176,9 @@ func TestMain_Encode(t *testing.T) {
 
 	// Data are invalid, it will fail..
 	assert.NotNil(t, err)
-	// .. But with a specific error
-	assert.Contains(t, err.Error(), "Invalid data")
+	// .. But with a specific error, ffprobe's reason included
+	assert.Contains(t, err.Error(), "invalid assets")
+	assert.Contains(t, err.Error(), "Invalid data found when processing input")
 }
 
 func TestMain_cleanUpFromObjectStoreOk(t *testing.T) {
@@ -197,6 +199,28 @@ func TestMain_cleanUpFromObjectStoreOk(t *testing.T) {
 	assert.Nil(t, err)
 }
 
+// Extras are uploaded under the keys listed in the result, an extra that can't be uploaded doesn't stop the others
+func TestMain_uploadExtras(t *testing.T) {
+	ctx := context.Background()
+	ctrl := gomock.NewController(t)
+	proxy := mock_object_storage.NewMockBindingProxy(ctrl)
+	proxy.EXPECT().
+		InvokeBinding(gomock.Any(), NewBidingMatcher("1.poster.jpg", "create")).
+		Return(&client.BindingEvent{}, nil)
+	proxy.EXPECT().
+		InvokeBinding(gomock.Any(), NewBidingMatcher("1.sprite.jpg", "create")).
+		Return(&client.BindingEvent{}, fmt.Errorf("test"))
+	objectStore := object_storage.NewObjectStorage(&ctx, proxy, "", b64)
+	eBox := encode_box.NewEncodeBox(&ctx, objectStore, &encode_box.EncodeBoxOptions{})
+	dir := t.TempDir()
+	eBox.Extras = map[string]string{"poster": filepath.Join(dir, "1.poster.jpg"), "sprite": filepath.Join(dir, "1.sprite.jpg")}
+	eBox.Result.Extras = map[string]string{"poster": "1.poster.jpg", "sprite": "1.sprite.jpg"}
+	for _, path := range eBox.Extras {
+		assert.Nil(t, os.WriteFile(path, []byte("a"), 0600))
+	}
+	uploadExtras(eBox, objectStore)
+}
+
 func TestMain_cleanUpFromObjectStoreError(t *testing.T) {
 	ctx := context.Background()
 	ctrl := gomock.NewController(t)
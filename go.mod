@@ -10,6 +10,7 @@ require (
 	github.com/stretchr/testify v1.8.4
 	go.elastic.co/ecslogrus v1.0.0
 	google.golang.org/grpc v1.56.2
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
@@ -24,5 +25,4 @@ require (
 	golang.org/x/text v0.11.0 // indirect
 	google.golang.org/genproto/googleapis/rpc v0.0.0-20230711160842-782d3b101e98 // indirect
 	google.golang.org/protobuf v1.31.0 // indirect
-	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
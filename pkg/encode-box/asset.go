@@ -2,6 +2,9 @@ package encode_box
 
 import (
 	"encode-box/pkg/encoder"
+	"errors"
+	"fmt"
+	"strings"
 	"time"
 )
 
@@ -21,6 +24,10 @@ const (
 	Audio
 	Image
 	SideAudio
+	Subtitle
+	Intro
+	Outro
+	Slide
 )
 
 // AssetCollection an enhanced array of pointer to assets
@@ -61,6 +68,33 @@ func NewAssetCollectionFrom(req *EncodingRequest) *AssetCollection {
 			media: Image,
 		})
 	}
+	// Or the images of a slideshow, in display order
+	for _, sKey := range req.ImageKeys {
+		allAssets = append(allAssets, &Asset{
+			key:   sKey,
+			media: Slide,
+		})
+	}
+	// Subtitles are optional
+	if req.SubtitleKey != "" {
+		allAssets = append(allAssets, &Asset{
+			key:   req.SubtitleKey,
+			media: Subtitle,
+		})
+	}
+	// Clips stitched around the main content are optional too
+	if req.IntroKey != "" {
+		allAssets = append(allAssets, &Asset{
+			key:   req.IntroKey,
+			media: Intro,
+		})
+	}
+	if req.OutroKey != "" {
+		allAssets = append(allAssets, &Asset{
+			key:   req.OutroKey,
+			media: Outro,
+		})
+	}
 	return &allAssets
 }
 
@@ -92,6 +126,63 @@ func (ac *AssetCollection) SideAudiosPaths() []string {
 	})
 }
 
+// SlidesPaths Get all paths of all slideshow images, in display order
+func (ac *AssetCollection) SlidesPaths() []string {
+	return ac.findPaths(func(asset *Asset) bool {
+		return asset.media == Slide
+	})
+}
+
+// SubtitlesPaths Get all paths of all subtitles assets
+func (ac *AssetCollection) SubtitlesPaths() []string {
+	return ac.findPaths(func(asset *Asset) bool {
+		return asset.media == Subtitle
+	})
+}
+
+// IntroPath Get the path of the intro clip, if any
+func (ac *AssetCollection) IntroPath() string {
+	return ac.findPath(Intro)
+}
+
+// OutroPath Get the path of the outro clip, if any
+func (ac *AssetCollection) OutroPath() string {
+	return ac.findPath(Outro)
+}
+
+// Roles of each asset media in encoding recipes
+var recipeRoles = map[AssetMedia]string{
+	Video:     encoder.VideoRole,
+	Audio:     encoder.AudioRole,
+	SideAudio: encoder.SideAudioRole,
+	Image:     encoder.ImageRole,
+	Slide:     encoder.SlideRole,
+	Subtitle:  encoder.SubtitleRole,
+	Intro:     encoder.IntroRole,
+	Outro:     encoder.OutroRole,
+}
+
+// RecipePaths Get all paths of all assets, indexed by recipe role
+func (ac *AssetCollection) RecipePaths() map[string][]string {
+	paths := map[string][]string{}
+	for _, a := range *ac {
+		role := recipeRoles[a.media]
+		paths[role] = append(paths[role], a.path)
+	}
+	return paths
+}
+
+// Return the path of the first asset of the given media, or an empty string
+func (ac *AssetCollection) findPath(media AssetMedia) string {
+	paths := ac.findPaths(func(asset *Asset) bool {
+		return asset.media == media
+	})
+	if len(paths) == 0 {
+		return ""
+	}
+	return paths[0]
+}
+
 // Return all paths of all assets satisfying the given predicate
 func (ac *AssetCollection) findPaths(predicate func(*Asset) bool) []string {
 	var ret []string
@@ -103,11 +194,108 @@ func (ac *AssetCollection) findPaths(predicate func(*Asset) bool) []string {
 	return ret
 }
 
-func (ac *AssetCollection) getOutputDuration() time.Duration {
+// Role of each media, as named in errors
+func (m AssetMedia) String() string {
+	switch m {
+	case Video:
+		return "video"
+	case Audio:
+		return "audio"
+	case Image:
+		return "image"
+	case SideAudio:
+		return "background audio"
+	case Subtitle:
+		return "subtitles"
+	case Intro:
+		return "intro"
+	case Outro:
+		return "outro"
+	case Slide:
+		return "slideshow image"
+	default:
+		return "unknown"
+	}
+}
+
+// Check that each downloaded asset has the streams its role requires, using probe to inspect them.
+// All invalid assets are reported, each error naming the asset key
+func (ac *AssetCollection) validate(probe func(path string) (*encoder.MediaInfo, error)) error {
+	var errs []error
+	for _, a := range *ac {
+		info, err := probe(a.path)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("asset \"%s\" is not a readable %s : %w", a.key, a.media, err))
+			continue
+		}
+		if reason := a.media.check(info); reason != "" {
+			errs = append(errs, fmt.Errorf("asset \"%s\" is not a valid %s : %s", a.key, a.media, reason))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Return why info is not suitable for the media role, or an empty string if it is
+func (m AssetMedia) check(info *encoder.MediaInfo) string {
+	switch m {
+	case Video:
+		if !info.HasVideo() {
+			return "no video stream"
+		}
+	case Audio, SideAudio:
+		if !info.HasAudio() {
+			return "no audio stream"
+		}
+	case Image, Slide:
+		// Images are read by the image2 demuxer, one of the "*_pipe" ones, or the gif one
+		if !strings.HasPrefix(info.Format.Name, "image2") && !strings.HasSuffix(info.Format.Name, "_pipe") && info.Format.Name != "gif" {
+			return fmt.Sprintf("%s is not an image format", info.Format.Name)
+		}
+		if !info.HasVideo() {
+			return "no image stream"
+		}
+	case Subtitle:
+		for _, s := range info.Streams {
+			if s.Type == encoder.SubtitleStream {
+				return ""
+			}
+		}
+		return "no subtitle stream"
+	case Intro, Outro:
+		// Clips are concatenated with the main content. A clip without audio is played along with silence
+		if !info.HasVideo() {
+			return "no video stream"
+		}
+	}
+	return ""
+}
+
+// Return the duration of the output, including the intro and outro clips. If mix is true,
+// the audio tracks are played simultaneously instead of one after the other
+func (ac *AssetCollection) getOutputDuration(mix bool) time.Duration {
+	maxDur := ac.getMainDuration(mix)
+
+	// Intro and outro are played before and after the main content
+	for _, a := range *ac {
+		if a.path != "" && (a.media == Intro || a.media == Outro) {
+			dur, err := encoder.GetDuration(a.path)
+			if err != nil {
+				log.Debugf("[Encode box] :: Could not get duration for asset %s, err : %s", a.path, err)
+				continue
+			}
+			maxDur += dur
+		}
+	}
+	return maxDur
+}
+
+// Return the duration of the main content of the output, without the intro and outro clips
+func (ac *AssetCollection) getMainDuration(mix bool) time.Duration {
 	var maxDur time.Duration
 	// Get maximum duration through all audios or videos assets
 
-	// The length of the final audio track is the length of the sum of all audio tracks
+	// The length of the final audio track is the length of the sum of all audio tracks,
+	// or of the longest one when they are mixed together
 	for _, a := range *ac {
 		if a.path != "" && a.media == Audio {
 			dur, err := encoder.GetDuration(a.path)
@@ -115,13 +303,17 @@ func (ac *AssetCollection) getOutputDuration() time.Duration {
 				log.Debugf("[Encode box] :: Could not get duration for asset %s, err : %s", a.path, err)
 				continue
 			}
-			maxDur += dur
+			if !mix {
+				maxDur += dur
+			} else if dur > maxDur {
+				maxDur = dur
+			}
 		}
 	}
 
-	// All other asset are in one unit, so we can directly compare
+	// All other asset are in one unit. Images and subtitles have no duration of their own, so we can directly compare
 	for _, a := range *ac {
-		if a.path != "" && a.media != Audio && a.media != Image {
+		if a.path != "" && a.media != Audio && a.media != Image && a.media != Slide && a.media != Subtitle && a.media != Intro && a.media != Outro {
 			dur, err := encoder.GetDuration(a.path)
 			if err != nil {
 				log.Debugf("[Encode box] :: Could not get duration for asset %s, err : %s", a.path, err)
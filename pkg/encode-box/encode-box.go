@@ -10,6 +10,7 @@ import (
 	"math"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 )
 
@@ -21,7 +22,23 @@ type EncodeBoxOptions struct {
 	// Number of time to retry calls made to the object store.
 	// Each call will be followed by a wait time of (2^attempt)s
 	ObjStoreMaxRetry int8
+	// Directory containing the fonts usable by text overlays. Default to DefaultFontsDir
+	FontsDir string
+	// Quality profile of requests not defining one, see EncodingOptions.Quality. Default to "standard"
+	DefaultQuality string
+	// Names of the FFmpeg filters requests can use as custom filters, see EncodingOptions.Filters.
+	// Custom filters are refused if empty
+	AllowedFilters []string
+	// Encoding recipes requests can use instead of the presets, indexed by name. See encoder.LoadRecipes
+	Recipes map[string]*encoder.Recipe
 }
+
+// DefaultFontsDir Directory of the bundled fonts, relative to the working directory
+const DefaultFontsDir = "resources/fonts"
+
+// DefaultRecipesDir Directory of the bundled encoding recipes, relative to the working directory
+const DefaultRecipesDir = "resources/recipes"
+
 type EncodeBox struct {
 	// Assets Downloader
 	Downloader *object_storage.ObjectStorage
@@ -36,6 +53,11 @@ type EncodeBox struct {
 	PChan chan console_parser.EncodingProgress
 	// Behaviour options
 	opt EncodeBoxOptions
+	// Information gathered while encoding, only complete once the encoding is done
+	Result EncodingResult
+	// Local paths of the files generated next to the output once encoded, indexed by kind
+	// ("poster", "sprite", "spriteIndex", "preview")
+	Extras map[string]string
 }
 
 func NewEncodeBox(ctx *context.Context, downloader *object_storage.ObjectStorage, opt *EncodeBoxOptions) *EncodeBox {
@@ -66,25 +88,42 @@ func (eb *EncodeBox) Encode(req *EncodingRequest, output string) {
 	if err != nil {
 		log.Errorf(`Error while downloading assets : %s`, err)
 		eb.EChan <- err
-	}
-	duration := allAssets.getOutputDuration()
-	if err != nil {
-		// Having an error here isn't that important, we can still continue
-		log.Warnf(`Unable to get the asset collection duration: %s`, err)
+		return
 	}
 	// Queue the assets cleaning up
 	defer eb.cleanUpAssets(allAssets)
+	// Make sure each asset can be used for its role, FFmpeg errors are way less explicit
+	if err := allAssets.validate(encoder.Probe); err != nil {
+		log.Errorf(`Invalid assets : %s`, err)
+		eb.EChan <- fmt.Errorf("invalid assets : %w", err)
+		return
+	}
+	duration := allAssets.getOutputDuration(req.Options.AudioLayout == "mix")
 	// Choose encoding method
 	// If no method found -> abort
 	enc, err := eb.setupEnc(req, allAssets, output)
 	if err != nil {
 		log.Errorf(`Error while setup encoding : %s`, err)
 		eb.EChan <- err
+		return
+	}
+	if enc.Video != nil {
+		if enc.Video.Copy {
+			log.Info("[Encode box] :: The source video is copied without re-encoding")
+		} else {
+			log.Infof("[Encode box] :: The source video is transcoded, as %s", enc.Video.Reason)
+		}
+		eb.Result.Video = enc.Video
+	}
+	// Removed silences won't be part of the output
+	for _, c := range eb.Result.Cuts {
+		duration -= seconds(c.End - c.Start)
 	}
 
 	// Finally, start the encoding process itself
 	log.Debugf("Now executing FFMPEG cmd : %s", enc.GetCommandLine())
 	go enc.Start()
+	failed := false
 	for {
 		select {
 		case p := <-enc.PChan:
@@ -92,13 +131,66 @@ func (eb *EncodeBox) Encode(req *EncodingRequest, output string) {
 			log.Debugf("%+v\n", p)
 			eb.PChan <- *p
 		case e := <-enc.EChan:
+			failed = true
 			eb.EChan <- fmt.Errorf("error while encoding : %w", e)
 		case <-enc.Ctx.Done():
+			if !failed {
+				eb.generateExtras(req, output)
+			}
 			return
 		}
 	}
 }
 
+// Generate the requested files derived from the output (poster, sprite sheet...), next to it.
+// These files are optional, a failure is only logged
+func (eb *EncodeBox) generateExtras(req *EncodingRequest, output string) {
+	base := strings.TrimSuffix(output, filepath.Ext(output))
+	extras := map[string]string{}
+	if req.Options.Poster != nil {
+		posterPath := base + ".poster.jpg"
+		var at *time.Duration
+		if req.Options.Poster.At != nil {
+			d := seconds(*req.Options.Poster.At)
+			at = &d
+		}
+		if err := encoder.ExtractPoster(&eb.Ctx, output, posterPath, at); err != nil {
+			log.Warnf("[Encode box] :: Could not extract poster : %s", err)
+		} else {
+			extras["poster"] = posterPath
+		}
+	}
+	if req.Options.Sprite != nil {
+		spritePath := base + ".sprite.jpg"
+		indexPath := base + ".sprite.vtt"
+		// The index is uploaded next to the sheet, it can reference it with a relative URL
+		err := encoder.GenerateSprite(&eb.Ctx, output, spritePath, indexPath, filepath.Base(spritePath), req.Options.Sprite.toEncoderOptions())
+		if err != nil {
+			log.Warnf("[Encode box] :: Could not generate sprite : %s", err)
+		} else {
+			extras["sprite"] = spritePath
+			extras["spriteIndex"] = indexPath
+		}
+	}
+	if req.Options.Preview != nil {
+		opt := req.Options.Preview.toEncoderOptions()
+		previewPath := fmt.Sprintf("%s.preview.%s", base, opt.Format.Extension())
+		if err := encoder.GeneratePreview(&eb.Ctx, output, previewPath, opt); err != nil {
+			log.Warnf("[Encode box] :: Could not generate preview : %s", err)
+		} else {
+			extras["preview"] = previewPath
+		}
+	}
+	if len(extras) > 0 {
+		eb.Extras = extras
+		// Extras are uploaded next to the output, named after their file
+		eb.Result.Extras = make(map[string]string, len(extras))
+		for kind, path := range extras {
+			eb.Result.Extras[kind] = filepath.Base(path)
+		}
+	}
+}
+
 // Concurrently download all assets required for the transcoding process
 // Modify in place the array pointer
 func (eb *EncodeBox) downloadAssets(assets *AssetCollection) error {
@@ -167,28 +259,81 @@ func (eb *EncodeBox) cleanUpAssets(assets *AssetCollection) {
 
 }
 
+// Return the directory containing the fonts usable by text overlays
+func (eb *EncodeBox) fontsDir() string {
+	if eb.opt.FontsDir == "" {
+		return DefaultFontsDir
+	}
+	return eb.opt.FontsDir
+}
+
 // Setup an Encoder instance with the downloaded assets
 func (eb *EncodeBox) setupEnc(req *EncodingRequest, assets *AssetCollection, output string) (*encoder.Encoder, error) {
 	var enc *encoder.Encoder
 	var err error
 
+	// A recipe replaces the presets, and describes the assets it accepts
+	if req.Recipe != "" {
+		return eb.setupRecipeEnc(req, assets, output)
+	}
+
 	// No audio tracks, cannot proceed
 	if len(req.AudiosKeys) == 0 {
 		return nil, fmt.Errorf("no suitable encoder found for %+v", req)
 
 	}
+	opt, err := req.toPresetOptions()
+	if err != nil {
+		return nil, fmt.Errorf("invalid encoding options : %w", err)
+	}
+	opt.Texts, err = makeTexts(req, eb.fontsDir())
+	if err != nil {
+		return nil, fmt.Errorf("invalid text overlays : %w", err)
+	}
+	opt.Quality, err = req.Options.toQualityOptions(eb.opt.DefaultQuality)
+	if err != nil {
+		return nil, fmt.Errorf("invalid encoding options : %w", err)
+	}
+	opt.AudioFilters, opt.VideoFilters, err = req.Options.toCustomFilters(eb.opt.AllowedFilters)
+	if err != nil {
+		return nil, fmt.Errorf("invalid custom filters : %w", err)
+	}
+	// Silences must be detected before building the encoder, as they will be cut from the main audio track
+	if req.Options.Silence != nil {
+		silence := req.Options.Silence.toEncoderOptions()
+		detection, err := encoder.DetectSilences(&eb.Ctx, assets.AudiosPaths(), opt, silence)
+		if err != nil {
+			return nil, fmt.Errorf("error while detecting silences : %w", err)
+		}
+		opt.Cuts = detection.Cuts(silence.TrimEdges, silence.MaxSilence)
+		eb.Result.Silences = toTimeRanges(detection.Silences)
+		eb.Result.Cuts = toTimeRanges(opt.Cuts)
+	}
+	opt.Chapters, err = makeChapters(req, assets, opt.Cuts)
+	if err != nil {
+		return nil, fmt.Errorf("invalid chapters : %w", err)
+	}
+	if subs := assets.SubtitlesPaths(); len(subs) != 0 {
+		opt.Subtitles = req.Options.Subtitles.toEncoderOptions(subs[0])
+	}
+	opt.Intro = assets.IntroPath()
+	opt.Outro = assets.OutroPath()
+	hasSlides := len(req.ImageKeys) > 0
 	// The encoder can be either an MainAudio/Video encoder
-	if req.VideoKey != "" && req.ImageKey == "" {
-		enc, err = encoder.GetAudiosVideoEnc(&eb.Ctx, assets.VideosPaths()[0], assets.AudiosPaths(), output)
-	} else if req.ImageKey != "" && req.VideoKey == "" {
+	if req.VideoKey != "" && req.ImageKey == "" && !hasSlides {
+		enc, err = encoder.GetAudiosVideoEnc(&eb.Ctx, assets.VideosPaths()[0], assets.AudiosPaths(), output, opt)
+	} else if req.ImageKey != "" && req.VideoKey == "" && !hasSlides {
 		// Or an image/video encoder
-		enc, err = encoder.GetAudiosImageEnc(&eb.Ctx, assets.ImagesPaths()[0], assets.AudiosPaths(), output)
+		enc, err = encoder.GetAudiosImageEnc(&eb.Ctx, assets.ImagesPaths()[0], assets.AudiosPaths(), output, opt)
+	} else if hasSlides && req.ImageKey == "" && req.VideoKey == "" {
+		// Or a slideshow encoder
+		enc, err = encoder.GetAudiosSlideshowEnc(&eb.Ctx, assets.SlidesPaths(), assets.AudiosPaths(), output, opt)
 	} else if req.ImageKey == "" && req.VideoKey == "" {
 		side := ""
 		if len(assets.SideAudiosPaths()) != 0 {
 			side = assets.SideAudiosPaths()[0]
 		}
-		enc, err = encoder.GetAudiosOnlyEnc(&eb.Ctx, assets.AudiosPaths(), side, output)
+		enc, err = encoder.GetAudiosOnlyEnc(&eb.Ctx, assets.AudiosPaths(), side, output, opt)
 	} else {
 		// If an unsupported assets set is passed, don't event try and error out
 		return nil, fmt.Errorf("no suitable encoder found for %+v", req)
@@ -200,9 +345,26 @@ func (eb *EncodeBox) setupEnc(req *EncodingRequest, assets *AssetCollection, out
 	return enc, nil
 }
 
+// Build an encoder following the recipe of the request
+func (eb *EncodeBox) setupRecipeEnc(req *EncodingRequest, assets *AssetCollection, output string) (*encoder.Encoder, error) {
+	recipe, ok := eb.opt.Recipes[req.Recipe]
+	if !ok {
+		return nil, fmt.Errorf("unknown recipe \"%s\"", req.Recipe)
+	}
+	enc, err := encoder.GetRecipeEnc(&eb.Ctx, recipe, assets.RecipePaths(), output)
+	if err != nil {
+		return nil, fmt.Errorf("error while creating encoder :  %w", err)
+	}
+	return enc, nil
+}
+
 type EncodingRequest struct {
 	// Record UUID
 	JobId string `json:"jobId"`
+	// Name of the encoding recipe to use instead of the presets. Presets options are then ignored
+	Recipe string `json:"recipe,omitempty"`
+	// Title of the record, usable in text overlays
+	Title string `json:"title"`
 	// Storage backend keys for all videos tracks
 	VideoKey string `json:"videoKey"`
 	// Storage backend keys for all main audio track part
@@ -211,12 +373,75 @@ type EncodingRequest struct {
 	BackgroundAudioKey string `json:"backgroundAudioKey" omitempty:"true"`
 	// Storage backend keys for the image track
 	ImageKey string `json:"imageKey"`
+	// Storage backend keys for the images of a slideshow, in display order. Can't be used with imageKey
+	ImageKeys []string `json:"imageKeys,omitempty"`
+	// Storage backend key for a subtitles file (SRT or WebVTT)
+	SubtitleKey string `json:"subtitleKey"`
+	// Storage backend keys for the clips played before and after the main content
+	IntroKey string `json:"introKey"`
+	OutroKey string `json:"outroKey"`
+	// Chapters of the output. If empty, chapters can still be generated with the autoChapters option
+	Chapters []Chapter `json:"chapters,omitempty"`
 	// All available options for encoding
 	Options EncodingOptions `json:"options"`
 }
 
+// Chapter A navigable section of the output
+type Chapter struct {
+	Title string `json:"title"`
+	// Start of the chapter in the main content (seconds). Chapters are delayed by the intro, if any
+	Start float64 `json:"start"`
+}
+
 // EncodingOptions All valid encoding options
 type EncodingOptions struct {
 	// Clean up used video/audio/images assets if the encoding succeeded
 	DeleteAssetsFromObjStore bool `json:"deleteAssetsFromObjStore"`
+	// Loudness normalization of the main audio track
+	Loudness LoudnessOptions `json:"loudness"`
+	// How the main audio tracks are combined, either "concat" (default) or "mix"
+	AudioLayout string `json:"audioLayout"`
+	// Processing chain applied on main audio tracks, indexed by audio key
+	Tracks map[string]encoder.TrackProcessing `json:"tracks"`
+	// Silence detection and removal in the main audio track. Disabled if nil
+	Silence *SilenceOptions `json:"silence,omitempty"`
+	// Generate a chapter for each audio part. Ignored if chapters are explicitly defined
+	AutoChapters bool `json:"autoChapters"`
+	// How the subtitles are added to the output. Ignored if there is no subtitleKey
+	Subtitles SubtitleOptions `json:"subtitles"`
+	// Animated visualization of the audio, drawn over the image or a plain background. Disabled if omitted
+	Visualization *VisualizationOptions `json:"visualization,omitempty"`
+	// Texts drawn over the video
+	Texts []TextOverlay `json:"texts,omitempty"`
+	// How the images of imageKeys are displayed
+	Slideshow SlideshowOptions `json:"slideshow"`
+	// Extract a poster image from the output. Disabled if omitted
+	Poster *PosterOptions `json:"poster,omitempty"`
+	// Generate a sprite sheet of thumbnails of the output, and its WebVTT index. Disabled if omitted
+	Sprite *SpriteOptions `json:"sprite,omitempty"`
+	// Generate a short animated preview of the output. Disabled if omitted
+	Preview *PreviewOptions `json:"preview,omitempty"`
+	// Encoding speed/quality trade-off of the video, either "draft", "standard" or "archive".
+	// Default to the encode box default quality
+	Quality string `json:"quality"`
+	// Codec of the video, either "h264" (default), "h265", "vp9" or "av1"
+	Codec string `json:"codec"`
+	// Sample rate, sample format and channel layout of the output audio. 44.1kHz stereo if omitted
+	Audio *AudioFormatOptions `json:"audio,omitempty"`
+	// Resolution and framerate of the output. Inputs keep their own size if omitted
+	Canvas *CanvasOptions `json:"canvas,omitempty"`
+	// Arbitrary FFmpeg filters, among the ones allowed by the encode box, applied in order
+	Filters []FilterOptions `json:"filters,omitempty"`
+}
+
+// EncodingResult Information gathered while processing a request, sent along the Done event
+type EncodingResult struct {
+	// Silences detected in the main audio track, if silence detection was requested
+	Silences []TimeRange `json:"silences,omitempty"`
+	// Parts of the main audio track removed from the output
+	Cuts []TimeRange `json:"cuts,omitempty"`
+	// Storage backend keys of the files generated next to the output, indexed by kind ("poster", "sprite", "spriteIndex", "preview")
+	Extras map[string]string `json:"extras,omitempty"`
+	// Whether the source video was copied or transcoded, only if there is a source video
+	Video *encoder.VideoDecision `json:"video,omitempty"`
 }
@@ -3,7 +3,9 @@ package encode_box
 import (
 	"context"
 	"encode-box/internal/mock/mock-object-storage"
+	"encode-box/pkg/encoder"
 	object_storage "encode-box/pkg/object-storage"
+	test_utils "encode-box/test-utils"
 	"fmt"
 	"github.com/dapr/go-sdk/client"
 	"github.com/golang/mock/gomock"
@@ -39,7 +41,7 @@ func TestEncodeBox_DownloadAssets(t *testing.T) {
 // The default asset colelction should have no path, so duuration cannot be retrieved
 func TestEncodeBox_DownloadAssets_GetDuration_NoDownload(t *testing.T) {
 	aCol := getAssetsCollection(1, 1, 0)
-	assert.Zero(t, aCol.getOutputDuration())
+	assert.Zero(t, aCol.getOutputDuration(false))
 }
 
 // It should not panic on invalid data
@@ -50,10 +52,22 @@ func TestEncodeBox_DownloadAssets_GetDuration_InvalidData(t *testing.T) {
 	aCol := getAssetsCollection(1, 1, 0)
 	err := eBox.downloadAssets(aCol)
 	assert.Nil(t, err)
-	assert.Zero(t, aCol.getOutputDuration())
+	assert.Zero(t, aCol.getOutputDuration(false))
 
 }
 
+// Mixed audio tracks are played simultaneously, the output lasts as long as the longest one
+func TestEncodeBox_GetOutputDuration_Mix(t *testing.T) {
+	aCol := getAssetsCollection(0, 2, 0)
+	for _, a := range *aCol {
+		a.path = test_utils.GetResAbsolutePath(t, test_utils.Audio)
+	}
+	single, err := encoder.GetDuration((*aCol)[0].path)
+	assert.Nil(t, err)
+	assert.Equal(t, 2*single, aCol.getOutputDuration(false))
+	assert.Equal(t, single, aCol.getOutputDuration(true))
+}
+
 func TestEncodeBox_DownloadAssetsErr(t *testing.T) {
 	proxy, eBox := Setup(t)
 	proxy.EXPECT().InvokeBinding(gomock.Any(), gomock.Any()).Return(&client.BindingEvent{Data: []byte("a")}, nil)
@@ -170,6 +184,69 @@ func TestEncodeBox_Encode(t *testing.T) {
 	}
 }
 
+func TestEncodeBox_AssetCollection_IntroOutro(t *testing.T) {
+	aCol := NewAssetCollectionFrom(&EncodingRequest{AudiosKeys: []string{"a"}, IntroKey: "intro", OutroKey: "outro"})
+	assert.Len(t, *aCol, 3)
+	// Nothing downloaded yet
+	assert.Empty(t, aCol.IntroPath())
+	for _, a := range *aCol {
+		a.path = "/tmp/" + a.key
+	}
+	assert.Equal(t, "/tmp/intro", aCol.IntroPath())
+	assert.Equal(t, "/tmp/outro", aCol.OutroPath())
+	assert.Equal(t, []string{"/tmp/a"}, aCol.AudiosPaths())
+}
+
+func TestEncodeBox_AssetCollection_Slides(t *testing.T) {
+	aCol := NewAssetCollectionFrom(&EncodingRequest{AudiosKeys: []string{"a"}, ImageKeys: []string{"s2", "s1", "s3"}})
+	for _, a := range *aCol {
+		a.path = "/tmp/" + a.key
+	}
+	// Display order must be kept
+	assert.Equal(t, []string{"/tmp/s2", "/tmp/s1", "/tmp/s3"}, aCol.SlidesPaths())
+	assert.Empty(t, aCol.ImagesPaths())
+}
+
+func TestEncodeBox_GenerateExtras_NoneRequested(t *testing.T) {
+	_, eBox := Setup(t)
+	eBox.generateExtras(&EncodingRequest{}, "/tmp/output.mp4")
+	assert.Nil(t, eBox.Extras)
+	assert.Nil(t, eBox.Result.Extras)
+}
+
+func TestEncodeBox_RecipePaths(t *testing.T) {
+	aCol := getAssetsCollection(1, 2, 1)
+	for _, a := range *aCol {
+		a.path = "/tmp/" + a.key
+	}
+	assert.Equal(t, map[string][]string{
+		encoder.VideoRole: {"/tmp/V_0"},
+		encoder.AudioRole: {"/tmp/A_0", "/tmp/A_1"},
+		encoder.ImageRole: {"/tmp/I_0"},
+	}, aCol.RecipePaths())
+}
+
+func TestEncodeBox_SetupRecipeEnc(t *testing.T) {
+	_, eBox := Setup(t)
+	recipes, err := encoder.LoadRecipes(filepath.Join(ResPath, "recipes"))
+	assert.NoError(t, err)
+	eBox.opt.Recipes = recipes
+	aCol := getAssetsCollection(1, 2, 1)
+	for _, a := range *aCol {
+		a.path = "/tmp/" + a.key
+	}
+	enc, err := eBox.setupEnc(&EncodingRequest{Recipe: "video-watermark"}, aCol, "/tmp/out.mp4")
+	assert.NoError(t, err)
+	assert.Contains(t, enc.GetCommandLine(), "overlay=")
+
+	// Unknown recipe
+	_, err = eBox.setupEnc(&EncodingRequest{Recipe: "unknown"}, aCol, "/tmp/out.mp4")
+	assert.ErrorContains(t, err, "unknown recipe")
+	// Assets not accepted by the recipe
+	_, err = eBox.setupEnc(&EncodingRequest{Recipe: "video-watermark"}, getAssetsCollection(0, 1, 1), "/tmp/out.mp4")
+	assert.Error(t, err)
+}
+
 // Returns an asset collection with the specified number of videos track, audio tracks and image tracks
 func getAssetsCollection(vidCount int, audCount int, imgCount int) *AssetCollection {
 	var aCol AssetCollection
@@ -222,3 +299,42 @@ func (m *bindingMatcher) Matches(x interface{}) bool {
 func (m *bindingMatcher) String() string {
 	return m.name
 }
+
+// Probe returning fixed media infos, indexed by path
+func fakeProbe(infos map[string]*encoder.MediaInfo) func(string) (*encoder.MediaInfo, error) {
+	return func(path string) (*encoder.MediaInfo, error) {
+		if info, ok := infos[path]; ok {
+			return info, nil
+		}
+		return nil, fmt.Errorf("invalid data found when processing input")
+	}
+}
+
+func TestEncodeBox_AssetCollection_Validate(t *testing.T) {
+	audio := &encoder.MediaInfo{Format: encoder.FormatInfo{Name: "mp3"}, Streams: []encoder.StreamInfo{{Type: encoder.AudioStream}}}
+	video := &encoder.MediaInfo{Format: encoder.FormatInfo{Name: "mov,mp4,m4a,3gp,3g2,mj2"}, Streams: []encoder.StreamInfo{{Type: encoder.VideoStream}, {Type: encoder.AudioStream}}}
+	image := &encoder.MediaInfo{Format: encoder.FormatInfo{Name: "png_pipe"}, Streams: []encoder.StreamInfo{{Type: encoder.VideoStream}}}
+	aCol := NewAssetCollectionFrom(&EncodingRequest{VideoKey: "v", AudiosKeys: []string{"a"}, ImageKey: "i", IntroKey: "intro"})
+	for _, a := range *aCol {
+		a.path = "/tmp/" + a.key
+	}
+	probe := fakeProbe(map[string]*encoder.MediaInfo{"/tmp/v": video, "/tmp/a": audio, "/tmp/i": image, "/tmp/intro": video})
+	assert.NoError(t, aCol.validate(probe))
+}
+
+func TestEncodeBox_AssetCollection_Validate_Invalid(t *testing.T) {
+	audio := &encoder.MediaInfo{Format: encoder.FormatInfo{Name: "mp3"}, Streams: []encoder.StreamInfo{{Type: encoder.AudioStream}}}
+	// An audio file with a cover is still not a video
+	cover := &encoder.MediaInfo{Format: encoder.FormatInfo{Name: "mp3"}, Streams: []encoder.StreamInfo{{Type: encoder.AudioStream}, {Type: encoder.VideoStream, AttachedPic: true}}}
+	aCol := NewAssetCollectionFrom(&EncodingRequest{VideoKey: "v", AudiosKeys: []string{"a", "broken"}, ImageKey: "i"})
+	for _, a := range *aCol {
+		a.path = "/tmp/" + a.key
+	}
+	probe := fakeProbe(map[string]*encoder.MediaInfo{"/tmp/v": cover, "/tmp/a": audio, "/tmp/i": audio})
+	err := aCol.validate(probe)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), `asset "v" is not a valid video : no video stream`)
+	assert.Contains(t, err.Error(), `asset "broken" is not a readable audio : invalid data found when processing input`)
+	assert.Contains(t, err.Error(), `asset "i" is not a valid image : mp3 is not an image format`)
+	assert.NotContains(t, err.Error(), `asset "a"`)
+}
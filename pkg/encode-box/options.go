@@ -0,0 +1,728 @@
+package encode_box
+
+import (
+	"encode-box/pkg/encoder"
+	"encode-box/pkg/encoder/filtergraph"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// LoudnessOptions Loudness normalization of the main audio track
+type LoudnessOptions struct {
+	// Either "speechnorm" (default), "loudnorm" (single pass EBU R128), "loudnorm-2pass" or "none"
+	Mode string `json:"mode"`
+	// Loudness targets profile, either "podcast" (default, -16 LUFS) or "broadcast" (-23 LUFS)
+	Preset string `json:"preset"`
+	// Override the preset integrated loudness target (LUFS)
+	Integrated *float64 `json:"integrated,omitempty"`
+	// Override the preset maximum true peak (dBTP)
+	TruePeak *float64 `json:"truePeak,omitempty"`
+	// Override the preset loudness range target (LU)
+	Range *float64 `json:"range,omitempty"`
+}
+
+// SilenceOptions Detection and removal of silences in the main audio track
+type SilenceOptions struct {
+	// Level under which the audio is considered silent (dB). Default to -50
+	Threshold float64 `json:"threshold"`
+	// Minimum duration of a silence (seconds). Default to 2
+	MinDuration float64 `json:"minDuration"`
+	// Remove the leading and trailing silences
+	TrimEdges bool `json:"trimEdges"`
+	// Shorten internal silences longer than this duration (seconds) to this duration. 0 to disable
+	MaxSilence float64 `json:"maxSilence"`
+}
+
+// SubtitleOptions How the subtitles are added to the output
+type SubtitleOptions struct {
+	// Either "soft" (default), as a subtitle track, or "burn", drawn over the video
+	Mode string `json:"mode"`
+	// ISO 639-2 language of the subtitles (eng, fra...), only used by soft subtitles
+	Language string `json:"language"`
+	// Appearance of burnt subtitles
+	Style filtergraph.SubtitleStyle `json:"style"`
+}
+
+// VisualizationOptions Animated visualization of the audio
+type VisualizationOptions struct {
+	// Either "waveform" (default), "spectrum" or "frequencies"
+	Mode string `json:"mode"`
+	// Size of the visualization (px). Default to 1280x240
+	Width  int `json:"width"`
+	Height int `json:"height"`
+	// Color of the waveform or the frequency bars. Default to white
+	Color string `json:"color"`
+	// Color of the background when there is no image. Default to black
+	Background string `json:"background"`
+	// Either "center" (default), "top" or "bottom"
+	Position string `json:"position"`
+}
+
+// TextOverlay A text drawn over the video
+type TextOverlay struct {
+	// Text to draw. "{jobId}" and "{title}" are replaced by the request values,
+	// "{timestamp}" by the current time of the video
+	Text string `json:"text"`
+	// Name of the font, without extension, among the fonts of the server. Default to DejaVuSans
+	Font string `json:"font"`
+	// Size of the font. Default to 36
+	FontSize int `json:"fontSize"`
+	// Color of the text. Default to white
+	Color string `json:"color"`
+	// Color of the box behind the text. No box if omitted
+	BoxColor string `json:"boxColor"`
+	// One of "bottom" (default), "bottom-left", "bottom-right", "top", "top-left", "top-right", "center"
+	Position string `json:"position"`
+	// Time window of the text (seconds). An end of 0 means until the end of the video
+	Start float64 `json:"start"`
+	End   float64 `json:"end"`
+}
+
+// CanvasOptions Resolution and framerate of the output
+type CanvasOptions struct {
+	// Size of the output (px). Both must be even
+	Width  int `json:"width"`
+	Height int `json:"height"`
+	// Default to 25
+	FrameRate int `json:"frameRate"`
+	// How inputs with another aspect ratio fit in the canvas :
+	// "letterbox" (default, black bars), "crop" or "blurpad" (blurred bars)
+	Fit string `json:"fit"`
+}
+
+// SlideshowOptions How the images of a slideshow are displayed.
+// Durations and timestamps can't be both used. Without them, all images are displayed for the same duration
+type SlideshowOptions struct {
+	// Display duration of each image (seconds). The last image is always displayed until the end of the audio
+	Durations []float64 `json:"durations,omitempty"`
+	// Time at which each image is displayed (seconds). The first one must be 0
+	Timestamps []float64 `json:"timestamps,omitempty"`
+	// Transition between two images, any xfade transition ("fade", "wipeleft", "slideup"...). No transition if omitted
+	Transition string `json:"transition"`
+	// Duration of each transition (seconds). Default to 1
+	TransitionDuration float64 `json:"transitionDuration"`
+	// Slowly zoom on each image
+	KenBurns bool `json:"kenBurns"`
+}
+
+// PosterOptions Image extracted from the output
+type PosterOptions struct {
+	// Time of the extracted frame (seconds). If omitted, the most representative frame is chosen
+	At *float64 `json:"at,omitempty"`
+}
+
+// SpriteOptions Sprite sheet of thumbnails of the output, used for scrubbing
+type SpriteOptions struct {
+	// Time between two thumbnails (seconds). Default to 10
+	Interval float64 `json:"interval"`
+	// Size of a single thumbnail. Default to 160x90
+	Width  int `json:"width"`
+	Height int `json:"height"`
+	// Number of thumbnails in each row of the sheet. Default to 10
+	Columns int `json:"columns"`
+}
+
+// PreviewOptions Short animated preview of the output
+type PreviewOptions struct {
+	// Offset of the preview in the output (seconds)
+	Start float64 `json:"start"`
+	// Length of the preview (seconds). Default to 5
+	Duration float64 `json:"duration"`
+	// Width of the preview, the height keeps the aspect ratio. Default to 480
+	Width int `json:"width"`
+	// Either "webp" (default), "gif" or "mp4" (muted)
+	Format string `json:"format"`
+}
+
+// Font used by text overlays without a font
+const defaultFont = "DejaVuSans"
+
+var textPositions = map[string]filtergraph.TextPosition{
+	"":             filtergraph.BottomCenter,
+	"bottom":       filtergraph.BottomCenter,
+	"bottom-left":  filtergraph.BottomLeft,
+	"bottom-right": filtergraph.BottomRight,
+	"top":          filtergraph.TopCenter,
+	"top-left":     filtergraph.TopLeft,
+	"top-right":    filtergraph.TopRight,
+	"center":       filtergraph.MiddleCenter,
+}
+
+// Convert the request options into options usable by the encoder presets
+func (req *EncodingRequest) toPresetOptions() (*encoder.PresetOptions, error) {
+	loudness, err := req.Options.Loudness.toEncoderOptions()
+	if err != nil {
+		return nil, err
+	}
+	opt := &encoder.PresetOptions{Loudness: *loudness}
+
+	switch req.Options.Subtitles.Mode {
+	case "", "soft", "burn":
+	default:
+		return nil, fmt.Errorf("unknown subtitles mode \"%s\"", req.Options.Subtitles.Mode)
+	}
+	if err := req.Options.Subtitles.validateStyle(); err != nil {
+		return nil, err
+	}
+
+	if err := req.Options.validateExtras(); err != nil {
+		return nil, err
+	}
+
+	slideshow, err := req.Options.Slideshow.toEncoderOptions(len(req.ImageKeys))
+	if err != nil {
+		return nil, err
+	}
+	opt.Slideshow = *slideshow
+
+	if req.Options.Canvas != nil {
+		canvas, err := req.Options.Canvas.toEncoderOptions()
+		if err != nil {
+			return nil, err
+		}
+		opt.Canvas = *canvas
+	}
+
+	if req.Options.Audio != nil {
+		opt.AudioFormat, err = req.Options.Audio.toEncoderOptions()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if req.Options.Visualization != nil {
+		opt.Visualization, err = req.Options.Visualization.toEncoderOptions()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	switch req.Options.AudioLayout {
+	case "", "concat":
+		opt.AudioLayout = encoder.ConcatLayout
+	case "mix":
+		opt.AudioLayout = encoder.MixLayout
+	default:
+		return nil, fmt.Errorf("unknown audio layout \"%s\"", req.Options.AudioLayout)
+	}
+
+	// Per-track processing is keyed by audio key, but the encoder expects it in the audio tracks order
+	for key, tp := range req.Options.Tracks {
+		if !contains(req.AudiosKeys, key) {
+			return nil, fmt.Errorf("track options defined for \"%s\", which is not an audio key", key)
+		}
+		if tp.Weight != nil && *tp.Weight < 0 {
+			return nil, fmt.Errorf("invalid weight %.2f for track \"%s\"", *tp.Weight, key)
+		}
+	}
+	if len(req.Options.Tracks) > 0 {
+		opt.Tracks = make([]*encoder.TrackProcessing, len(req.AudiosKeys))
+		for i, key := range req.AudiosKeys {
+			if tp, ok := req.Options.Tracks[key]; ok {
+				opt.Tracks[i] = &tp
+			}
+		}
+	}
+	return opt, nil
+}
+
+func (lo *LoudnessOptions) toEncoderOptions() (*encoder.LoudnessOptions, error) {
+	opt := &encoder.LoudnessOptions{}
+	switch lo.Mode {
+	case "", "speechnorm":
+		opt.Mode = encoder.SpeechnormLoudness
+	case "loudnorm":
+		opt.Mode = encoder.SinglePassLoudness
+	case "loudnorm-2pass":
+		opt.Mode = encoder.TwoPassLoudness
+	case "none":
+		opt.Mode = encoder.NoLoudness
+	default:
+		return nil, fmt.Errorf("unknown loudness mode \"%s\"", lo.Mode)
+	}
+
+	switch lo.Preset {
+	case "", "podcast":
+		opt.Targets = filtergraph.PodcastLoudness()
+	case "broadcast":
+		opt.Targets = filtergraph.BroadcastLoudness()
+	default:
+		return nil, fmt.Errorf("unknown loudness preset \"%s\"", lo.Preset)
+	}
+	// Any explicit target overrides the preset
+	if lo.Integrated != nil {
+		opt.Targets.I = *lo.Integrated
+	}
+	if lo.TruePeak != nil {
+		opt.Targets.TP = *lo.TruePeak
+	}
+	if lo.Range != nil {
+		opt.Targets.LRA = *lo.Range
+	}
+	return opt, nil
+}
+
+// Return true if the slice contains the given value
+func contains(slice []string, value string) bool {
+	for _, v := range slice {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func (so *SilenceOptions) toEncoderOptions() encoder.SilenceOptions {
+	return encoder.SilenceOptions{
+		Threshold:   so.Threshold,
+		MinDuration: seconds(so.MinDuration),
+		TrimEdges:   so.TrimEdges,
+		MaxSilence:  seconds(so.MaxSilence),
+	}
+}
+
+// Convert a number of seconds into a Duration
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
+
+// TimeRange A part of the main audio track, in seconds
+type TimeRange struct {
+	Start float64 `json:"start"`
+	End   float64 `json:"end"`
+}
+
+// Convert ranges of the encoder into ranges in seconds, as reported in the Done event
+func toTimeRanges(ranges []filtergraph.TimeRange) []TimeRange {
+	converted := make([]TimeRange, 0, len(ranges))
+	for _, r := range ranges {
+		converted = append(converted, TimeRange{Start: r.Start.Seconds(), End: r.End.Seconds()})
+	}
+	return converted
+}
+
+// Resolve the chapters of the output, either explicitly requested or one for each audio part
+func makeChapters(req *EncodingRequest, assets *AssetCollection, cuts []filtergraph.TimeRange) ([]encoder.Chapter, error) {
+	var chapters []encoder.Chapter
+	if len(req.Chapters) > 0 {
+		for _, c := range req.Chapters {
+			if c.Start < 0 {
+				return nil, fmt.Errorf("chapter \"%s\" starts before the beginning of the output", c.Title)
+			}
+			chapters = append(chapters, encoder.Chapter{Title: c.Title, Start: seconds(c.Start)})
+		}
+		sort.SliceStable(chapters, func(i, j int) bool { return chapters[i].Start < chapters[j].Start })
+	} else if req.Options.AutoChapters {
+		// Parts of a mix are played simultaneously, there is no boundary between them
+		if req.Options.AudioLayout == "mix" {
+			return nil, fmt.Errorf("auto chapters cannot be used with the mix audio layout")
+		}
+		var err error
+		chapters, err = encoder.AudioPartsChapters(assets.AudiosPaths())
+		if err != nil {
+			return nil, err
+		}
+		chapters = encoder.ShiftForCuts(chapters, cuts)
+	} else {
+		return nil, nil
+	}
+
+	// Each chapter ends when the next one starts, and the last one at the end of the main content.
+	// Chapters are delayed by the intro duration afterwards, when stitching the clips
+	total := assets.getMainDuration(req.Options.AudioLayout == "mix") - encoder.CutDuration(cuts)
+	var valid []encoder.Chapter
+	for i, c := range chapters {
+		c.End = total
+		if i+1 < len(chapters) {
+			c.End = chapters[i+1].Start
+		}
+		if c.End <= c.Start {
+			log.Warnf("[Encode box] :: Ignoring empty chapter \"%s\"", c.Title)
+			continue
+		}
+		valid = append(valid, c)
+	}
+	return valid, nil
+}
+
+// Check the burnt subtitles style, which is written as is in the filtergraph
+func (so *SubtitleOptions) validateStyle() error {
+	if so.Style.FontName != "" && !fontNameRegex.MatchString(so.Style.FontName) {
+		return fmt.Errorf("invalid subtitles font name \"%s\"", so.Style.FontName)
+	}
+	for _, color := range []string{so.Style.PrimaryColor, so.Style.OutlineColor} {
+		if color != "" && !filtergraph.IsColor(color) {
+			return fmt.Errorf("invalid subtitles color \"%s\"", color)
+		}
+	}
+	return nil
+}
+
+func (so *SubtitleOptions) toEncoderOptions(path string) *encoder.SubtitleOptions {
+	return &encoder.SubtitleOptions{
+		Path:     path,
+		Burn:     so.Mode == "burn",
+		Language: so.Language,
+		Style:    so.Style,
+	}
+}
+
+func (vo *VisualizationOptions) toEncoderOptions() (*encoder.VisualizationOptions, error) {
+	opt := &encoder.VisualizationOptions{
+		VisualizationParams: filtergraph.VisualizationParams{
+			Width:  vo.Width,
+			Height: vo.Height,
+			Color:  vo.Color,
+		},
+		Background: vo.Background,
+	}
+	switch vo.Mode {
+	case "", "waveform":
+		opt.Mode = filtergraph.Waveform
+	case "spectrum":
+		opt.Mode = filtergraph.Spectrum
+	case "frequencies":
+		opt.Mode = filtergraph.Frequencies
+	default:
+		return nil, fmt.Errorf("unknown visualization mode \"%s\"", vo.Mode)
+	}
+	switch vo.Position {
+	case "", "center":
+		opt.Position = filtergraph.Center
+	case "top":
+		opt.Position = filtergraph.Top
+	case "bottom":
+		opt.Position = filtergraph.Bottom
+	default:
+		return nil, fmt.Errorf("unknown visualization position \"%s\"", vo.Position)
+	}
+	if vo.Width < 0 || vo.Height < 0 {
+		return nil, fmt.Errorf("invalid visualization size %dx%d", vo.Width, vo.Height)
+	}
+	// Colors are written as is in the filtergraph
+	for _, color := range []string{vo.Color, vo.Background} {
+		if color != "" && !filtergraph.IsColor(color) {
+			return nil, fmt.Errorf("invalid visualization color \"%s\"", color)
+		}
+	}
+	return opt, nil
+}
+
+// Convert the slideshow options for a slideshow of imageCount images
+func (so *SlideshowOptions) toEncoderOptions(imageCount int) (*encoder.SlideshowOptions, error) {
+	opt := &encoder.SlideshowOptions{
+		Transition:         so.Transition,
+		TransitionDuration: seconds(so.TransitionDuration),
+		KenBurns:           so.KenBurns,
+	}
+	if so.Transition != "" && !filtergraph.IsTransition(so.Transition) {
+		return nil, fmt.Errorf("unknown slideshow transition \"%s\"", so.Transition)
+	}
+	if so.TransitionDuration < 0 {
+		return nil, fmt.Errorf("invalid slideshow transition duration %.2f", so.TransitionDuration)
+	}
+	if len(so.Durations) > 0 && len(so.Timestamps) > 0 {
+		return nil, fmt.Errorf("slideshow durations and timestamps can't be both used")
+	}
+
+	if len(so.Durations) > 0 {
+		if len(so.Durations) != imageCount {
+			return nil, fmt.Errorf("%d slideshow durations given for %d images", len(so.Durations), imageCount)
+		}
+		for _, d := range so.Durations {
+			if d <= 0 {
+				return nil, fmt.Errorf("invalid slideshow duration %.2f", d)
+			}
+			opt.Durations = append(opt.Durations, seconds(d))
+		}
+	}
+
+	if len(so.Timestamps) > 0 {
+		if len(so.Timestamps) != imageCount {
+			return nil, fmt.Errorf("%d slideshow timestamps given for %d images", len(so.Timestamps), imageCount)
+		}
+		if so.Timestamps[0] != 0 {
+			return nil, fmt.Errorf("the first slideshow image must be displayed at 0")
+		}
+		// Each image is displayed until the next one
+		for i := 1; i < len(so.Timestamps); i++ {
+			if so.Timestamps[i] <= so.Timestamps[i-1] {
+				return nil, fmt.Errorf("slideshow timestamps must be increasing")
+			}
+			opt.Durations = append(opt.Durations, seconds(so.Timestamps[i]-so.Timestamps[i-1]))
+		}
+	}
+	return opt, nil
+}
+
+// Check the options of the files generated from the output
+func (eo *EncodingOptions) validateExtras() error {
+	if eo.Poster != nil && eo.Poster.At != nil && *eo.Poster.At < 0 {
+		return fmt.Errorf("invalid poster time %.2f", *eo.Poster.At)
+	}
+	if so := eo.Sprite; so != nil {
+		if so.Interval < 0 || so.Width < 0 || so.Height < 0 || so.Columns < 0 {
+			return fmt.Errorf("invalid sprite options %+v", *so)
+		}
+		// Thumbnails are letterboxed, which requires even dimensions
+		if so.Width%2 != 0 || so.Height%2 != 0 {
+			return fmt.Errorf("invalid sprite thumbnail size %dx%d, dimensions must be even", so.Width, so.Height)
+		}
+	}
+	if po := eo.Preview; po != nil {
+		if po.Start < 0 || po.Duration < 0 || po.Duration > maxPreviewDuration {
+			return fmt.Errorf("invalid preview window, start %.2f, duration %.2f (max %d)", po.Start, po.Duration, maxPreviewDuration)
+		}
+		// Scaled with the aspect ratio kept, the height is even only if the width also is
+		if po.Width < 0 || po.Width%2 != 0 {
+			return fmt.Errorf("invalid preview width %d, it must be even", po.Width)
+		}
+		if _, ok := previewFormats[po.Format]; !ok {
+			return fmt.Errorf("unknown preview format %s", po.Format)
+		}
+	}
+	return nil
+}
+
+func (po *PreviewOptions) toEncoderOptions() encoder.PreviewOptions {
+	return encoder.PreviewOptions{
+		Start:    seconds(po.Start),
+		Duration: seconds(po.Duration),
+		Width:    po.Width,
+		Format:   previewFormats[po.Format],
+	}
+}
+
+// AudioFormatOptions Format of the output audio track
+type AudioFormatOptions struct {
+	// Samples per second (Hz), either 22050, 32000, 44100 (default), 48000 or 96000
+	SampleRate int `json:"sampleRate"`
+	// Representation of each sample, either "fltp" (default), "s16" or "s32"
+	SampleFormat string `json:"sampleFormat"`
+	// Either "mono", "stereo" (default) or "5.1"
+	Channels string `json:"channels"`
+}
+
+var sampleRates = map[int]filtergraph.Sampling{
+	0:     filtergraph.K44,
+	22050: filtergraph.K22,
+	32000: filtergraph.K32,
+	44100: filtergraph.K44,
+	48000: filtergraph.K48,
+	96000: filtergraph.K96,
+}
+
+var sampleFormats = map[string]filtergraph.SampleFormat{
+	"":     filtergraph.Fltp,
+	"fltp": filtergraph.Fltp,
+	"s16":  filtergraph.S16,
+	"s32":  filtergraph.S32,
+}
+
+var channelLayouts = map[string]filtergraph.ChannelLayout{
+	"":       filtergraph.Stereo,
+	"mono":   filtergraph.Mono,
+	"stereo": filtergraph.Stereo,
+	"5.1":    filtergraph.Surround51,
+}
+
+func (afo *AudioFormatOptions) toEncoderOptions() (filtergraph.AudioFormat, error) {
+	rate, ok := sampleRates[afo.SampleRate]
+	if !ok {
+		return filtergraph.AudioFormat{}, fmt.Errorf("unsupported audio sample rate %d", afo.SampleRate)
+	}
+	format, ok := sampleFormats[afo.SampleFormat]
+	if !ok {
+		return filtergraph.AudioFormat{}, fmt.Errorf("unknown audio sample format \"%s\"", afo.SampleFormat)
+	}
+	layout, ok := channelLayouts[afo.Channels]
+	if !ok {
+		return filtergraph.AudioFormat{}, fmt.Errorf("unknown audio channel layout \"%s\"", afo.Channels)
+	}
+	return filtergraph.AudioFormat{SampleRate: rate, SampleFormat: format, ChannelLayout: layout}, nil
+}
+
+var qualityProfiles = map[string]encoder.QualityProfile{
+	"standard": encoder.StandardQuality,
+	"draft":    encoder.DraftQuality,
+	"archive":  encoder.ArchiveQuality,
+}
+
+var videoCodecs = map[string]encoder.VideoCodec{
+	"":     encoder.H264,
+	"h264": encoder.H264,
+	"h265": encoder.H265,
+	"vp9":  encoder.VP9,
+	"av1":  encoder.AV1,
+}
+
+// IsQualityProfile Return true if name is a known quality profile
+func IsQualityProfile(name string) bool {
+	_, ok := qualityProfiles[name]
+	return ok
+}
+
+// Resolve the codec and quality profile of the video, using defaultQuality if the
+// request does not define any profile
+func (eo *EncodingOptions) toQualityOptions(defaultQuality string) (encoder.QualityOptions, error) {
+	name := eo.Quality
+	if name == "" {
+		name = defaultQuality
+	}
+	profile := encoder.StandardQuality
+	if name != "" {
+		var ok bool
+		if profile, ok = qualityProfiles[name]; !ok {
+			return encoder.QualityOptions{}, fmt.Errorf("unknown quality profile \"%s\"", name)
+		}
+	}
+	codec, ok := videoCodecs[eo.Codec]
+	if !ok {
+		return encoder.QualityOptions{}, fmt.Errorf("unknown video codec \"%s\"", eo.Codec)
+	}
+	return encoder.QualityOptions{Profile: profile, Codec: codec}, nil
+}
+
+// Longest preview accepted (seconds). Animated images get really heavy past a few seconds
+const maxPreviewDuration = 30
+
+var previewFormats = map[string]encoder.PreviewFormat{
+	"":     encoder.PreviewWebP,
+	"webp": encoder.PreviewWebP,
+	"gif":  encoder.PreviewGIF,
+	"mp4":  encoder.PreviewMP4,
+}
+
+func (so *SpriteOptions) toEncoderOptions() encoder.SpriteOptions {
+	return encoder.SpriteOptions{
+		Interval: seconds(so.Interval),
+		Width:    so.Width,
+		Height:   so.Height,
+		Columns:  so.Columns,
+	}
+}
+
+// FilterOptions An arbitrary FFmpeg filter, with a single input and a single output
+type FilterOptions struct {
+	// Name of the FFmpeg filter, such as "aecho" or "colorchannelmixer"
+	Name string `json:"name"`
+	// Track the filter is applied on, either "audio" (the main audio track) or "video"
+	Stream string `json:"stream"`
+	// Options of the filter, in order. An option without key is a positional one
+	Options []filtergraph.RawOption `json:"options,omitempty"`
+}
+
+// Most custom filters accepted in a single request
+const maxCustomFilters = 16
+
+// Split the custom filters of the request between the main audio track and the video track,
+// refusing any filter not in allowed
+func (eo *EncodingOptions) toCustomFilters(allowed []string) ([]encoder.CustomFilter, []encoder.CustomFilter, error) {
+	if len(eo.Filters) > maxCustomFilters {
+		return nil, nil, fmt.Errorf("too many custom filters (%d), at most %d are allowed", len(eo.Filters), maxCustomFilters)
+	}
+	var audio, video []encoder.CustomFilter
+	for _, f := range eo.Filters {
+		if !contains(allowed, f.Name) {
+			return nil, nil, fmt.Errorf("filter \"%s\" is not allowed", f.Name)
+		}
+		for _, o := range f.Options {
+			if o.Key != "" && !filtergraph.IsValidOptionKey(o.Key) {
+				return nil, nil, fmt.Errorf("invalid option key \"%s\" for filter \"%s\"", o.Key, f.Name)
+			}
+		}
+		custom := encoder.CustomFilter{Name: f.Name, Options: f.Options}
+		switch f.Stream {
+		case "audio":
+			audio = append(audio, custom)
+		case "video":
+			video = append(video, custom)
+		default:
+			return nil, nil, fmt.Errorf("unknown stream \"%s\" for filter \"%s\", either \"audio\" or \"video\"", f.Stream, f.Name)
+		}
+	}
+	return audio, video, nil
+}
+
+// Largest dimension and framerate accepted for the canvas
+const (
+	maxCanvasSize      = 4096
+	maxCanvasFrameRate = 120
+)
+
+func (co *CanvasOptions) toEncoderOptions() (*filtergraph.Canvas, error) {
+	canvas := &filtergraph.Canvas{Width: co.Width, Height: co.Height, FrameRate: co.FrameRate}
+	if canvas.FrameRate == 0 {
+		canvas.FrameRate = filtergraph.DefaultCanvas.FrameRate
+	}
+	switch co.Fit {
+	case "", "letterbox":
+		canvas.Fit = filtergraph.Letterbox
+	case "crop":
+		canvas.Fit = filtergraph.Crop
+	case "blurpad":
+		canvas.Fit = filtergraph.BlurPad
+	default:
+		return nil, fmt.Errorf("unknown canvas fit mode \"%s\"", co.Fit)
+	}
+	// libx264 can't encode odd dimensions
+	for _, size := range []int{co.Width, co.Height} {
+		if size <= 0 || size > maxCanvasSize || size%2 != 0 {
+			return nil, fmt.Errorf("invalid canvas size %dx%d, dimensions must be even and between 2 and %d", co.Width, co.Height, maxCanvasSize)
+		}
+	}
+	if canvas.FrameRate < 0 || canvas.FrameRate > maxCanvasFrameRate {
+		return nil, fmt.Errorf("invalid canvas framerate %d", co.FrameRate)
+	}
+	return canvas, nil
+}
+
+// Fonts are referenced by name, they can't be arbitrary paths
+var fontNameRegex = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
+
+// Resolve the text overlays of the request, using the fonts found in fontsDir
+func makeTexts(req *EncodingRequest, fontsDir string) ([]filtergraph.TextParams, error) {
+	templates := strings.NewReplacer("{jobId}", req.JobId, "{title}", req.Title)
+	var texts []filtergraph.TextParams
+	for _, to := range req.Options.Texts {
+		position, ok := textPositions[to.Position]
+		if !ok {
+			return nil, fmt.Errorf("unknown text position \"%s\"", to.Position)
+		}
+		font := to.Font
+		if font == "" {
+			font = defaultFont
+		}
+		if !fontNameRegex.MatchString(font) {
+			return nil, fmt.Errorf("invalid font name \"%s\"", font)
+		}
+		fontFile := filepath.Join(fontsDir, font+".ttf")
+		if _, err := os.Stat(fontFile); err != nil {
+			return nil, fmt.Errorf("font \"%s\" is not available", font)
+		}
+		for _, color := range []string{to.Color, to.BoxColor} {
+			if color != "" && !filtergraph.IsColor(color) {
+				return nil, fmt.Errorf("invalid text color \"%s\"", color)
+			}
+		}
+		if to.FontSize < 0 || to.Start < 0 || to.End < 0 || (to.End > 0 && to.End <= to.Start) {
+			return nil, fmt.Errorf("invalid size or time window for text \"%s\"", to.Text)
+		}
+		texts = append(texts, filtergraph.TextParams{
+			Text:     templates.Replace(to.Text),
+			FontFile: fontFile,
+			FontSize: to.FontSize,
+			Color:    to.Color,
+			BoxColor: to.BoxColor,
+			Position: position,
+			Start:    seconds(to.Start),
+			End:      seconds(to.End),
+		})
+	}
+	return texts, nil
+}
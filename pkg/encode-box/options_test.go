@@ -0,0 +1,398 @@
+package encode_box
+
+import (
+	"encode-box/pkg/encoder"
+	"encode-box/pkg/encoder/filtergraph"
+	test_utils "encode-box/test-utils"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"path"
+	"testing"
+	"time"
+)
+
+func TestOptions_Loudness_Default(t *testing.T) {
+	opt, err := (&LoudnessOptions{}).toEncoderOptions()
+	assert.NoError(t, err)
+	assert.Equal(t, encoder.SpeechnormLoudness, opt.Mode)
+	assert.Equal(t, filtergraph.PodcastLoudness(), opt.Targets)
+}
+
+func TestOptions_Loudness_PresetWithOverride(t *testing.T) {
+	tp := -2.0
+	opt, err := (&LoudnessOptions{Mode: "loudnorm-2pass", Preset: "broadcast", TruePeak: &tp}).toEncoderOptions()
+	assert.NoError(t, err)
+	assert.Equal(t, encoder.TwoPassLoudness, opt.Mode)
+	assert.Equal(t, filtergraph.LoudnormTargets{I: -23, TP: -2, LRA: 7}, opt.Targets)
+}
+
+func TestOptions_Loudness_None(t *testing.T) {
+	opt, err := (&LoudnessOptions{Mode: "none"}).toEncoderOptions()
+	assert.NoError(t, err)
+	assert.Equal(t, encoder.NoLoudness, opt.Mode)
+}
+
+func TestOptions_Loudness_Invalid(t *testing.T) {
+	_, err := (&LoudnessOptions{Mode: "unknown"}).toEncoderOptions()
+	assert.Error(t, err)
+	_, err = (&LoudnessOptions{Preset: "unknown"}).toEncoderOptions()
+	assert.Error(t, err)
+}
+
+func TestOptions_Tracks_Order(t *testing.T) {
+	req := &EncodingRequest{
+		AudiosKeys: []string{"a", "b", "c"},
+		Options: EncodingOptions{
+			AudioLayout: "mix",
+			Tracks: map[string]encoder.TrackProcessing{
+				"c": {Highpass: 80, Gain: 2},
+			},
+		},
+	}
+	opt, err := req.toPresetOptions()
+	assert.NoError(t, err)
+	assert.Equal(t, encoder.MixLayout, opt.AudioLayout)
+	assert.Len(t, opt.Tracks, 3)
+	assert.Nil(t, opt.Tracks[0])
+	assert.Nil(t, opt.Tracks[1])
+	assert.Equal(t, 80, opt.Tracks[2].Highpass)
+}
+
+func TestOptions_Tracks_UnknownKey(t *testing.T) {
+	req := &EncodingRequest{
+		AudiosKeys: []string{"a"},
+		Options: EncodingOptions{
+			Tracks: map[string]encoder.TrackProcessing{"z": {Gain: 2}},
+		},
+	}
+	_, err := req.toPresetOptions()
+	assert.Error(t, err)
+}
+
+func TestOptions_AudioLayout_Invalid(t *testing.T) {
+	req := &EncodingRequest{AudiosKeys: []string{"a"}, Options: EncodingOptions{AudioLayout: "stack"}}
+	_, err := req.toPresetOptions()
+	assert.Error(t, err)
+}
+
+func TestOptions_Tracks_NegativeWeight(t *testing.T) {
+	weight := float32(-1)
+	req := &EncodingRequest{
+		AudiosKeys: []string{"a", "b"},
+		Options: EncodingOptions{
+			AudioLayout: "mix",
+			Tracks:      map[string]encoder.TrackProcessing{"b": {Weight: &weight}},
+		},
+	}
+	_, err := req.toPresetOptions()
+	assert.Error(t, err)
+}
+
+func TestOptions_Silence(t *testing.T) {
+	so := &SilenceOptions{MinDuration: 1.5, TrimEdges: true, MaxSilence: 3}
+	opt := so.toEncoderOptions()
+	assert.Equal(t, 1500*time.Millisecond, opt.MinDuration)
+	assert.Equal(t, 3*time.Second, opt.MaxSilence)
+	assert.True(t, opt.TrimEdges)
+	// Default threshold is left to the encoder
+	assert.Zero(t, opt.Threshold)
+}
+
+func TestOptions_Chapters_None(t *testing.T) {
+	chapters, err := makeChapters(&EncodingRequest{AudiosKeys: []string{"a"}}, getAssetsCollection(0, 1, 0), nil)
+	assert.NoError(t, err)
+	assert.Nil(t, chapters)
+}
+
+func TestOptions_Chapters_Invalid(t *testing.T) {
+	req := &EncodingRequest{AudiosKeys: []string{"a"}, Chapters: []Chapter{{Title: "a", Start: -1}}}
+	_, err := makeChapters(req, getAssetsCollection(0, 1, 0), nil)
+	assert.Error(t, err)
+
+	// Mixed tracks have no boundaries
+	req = &EncodingRequest{AudiosKeys: []string{"a"}, Options: EncodingOptions{AutoChapters: true, AudioLayout: "mix"}}
+	_, err = makeChapters(req, getAssetsCollection(0, 1, 0), nil)
+	assert.Error(t, err)
+}
+
+// The last chapter ends with the main content : the intro duration is only added when the chapters are delayed
+func TestOptions_Chapters_WithIntro(t *testing.T) {
+	audio := test_utils.GetResAbsolutePath(t, test_utils.Audio)
+	assets := &AssetCollection{
+		{key: "a", media: Audio, path: audio},
+		{key: "i", media: Intro, path: test_utils.GetResAbsolutePath(t, test_utils.Video)},
+	}
+	req := &EncodingRequest{AudiosKeys: []string{"a"}, IntroKey: "i", Chapters: []Chapter{{Title: "a", Start: 0}}}
+	chapters, err := makeChapters(req, assets, nil)
+	assert.NoError(t, err)
+	duration, err := encoder.GetDuration(audio)
+	assert.NoError(t, err)
+	assert.Equal(t, []encoder.Chapter{{Title: "a", Start: 0, End: duration}}, chapters)
+}
+
+func TestOptions_Subtitles(t *testing.T) {
+	so := SubtitleOptions{Mode: "burn", Language: "fra", Style: filtergraph.SubtitleStyle{FontSize: 20}}
+	opt := so.toEncoderOptions("/tmp/sub.srt")
+	assert.Equal(t, &encoder.SubtitleOptions{
+		Path:     "/tmp/sub.srt",
+		Burn:     true,
+		Language: "fra",
+		Style:    filtergraph.SubtitleStyle{FontSize: 20},
+	}, opt)
+	assert.False(t, (&SubtitleOptions{}).toEncoderOptions("/tmp/sub.srt").Burn)
+}
+
+func TestOptions_Subtitles_InvalidStyle(t *testing.T) {
+	for _, style := range []filtergraph.SubtitleStyle{
+		{PrimaryColor: "#FFF,Bold=1"},
+		{OutlineColor: "red'"},
+		{FontName: "Arial,Outline=9"},
+	} {
+		req := &EncodingRequest{AudiosKeys: []string{"a"}, Options: EncodingOptions{Subtitles: SubtitleOptions{Mode: "burn", Style: style}}}
+		_, err := req.toPresetOptions()
+		assert.Error(t, err, style)
+	}
+	req := &EncodingRequest{AudiosKeys: []string{"a"}, Options: EncodingOptions{Subtitles: SubtitleOptions{
+		Mode:  "burn",
+		Style: filtergraph.SubtitleStyle{FontName: "DejaVuSans", PrimaryColor: "#FFFF00", OutlineColor: "0x000000"},
+	}}}
+	_, err := req.toPresetOptions()
+	assert.NoError(t, err)
+}
+
+func TestOptions_Subtitles_InvalidMode(t *testing.T) {
+	req := &EncodingRequest{AudiosKeys: []string{"a"}, Options: EncodingOptions{Subtitles: SubtitleOptions{Mode: "hard"}}}
+	_, err := req.toPresetOptions()
+	assert.Error(t, err)
+}
+
+func TestOptions_Visualization(t *testing.T) {
+	opt, err := (&VisualizationOptions{Mode: "spectrum", Width: 640, Height: 360, Background: "#202020", Position: "bottom"}).toEncoderOptions()
+	assert.NoError(t, err)
+	assert.Equal(t, &encoder.VisualizationOptions{
+		VisualizationParams: filtergraph.VisualizationParams{Mode: filtergraph.Spectrum, Width: 640, Height: 360, Position: filtergraph.Bottom},
+		Background:          "#202020",
+	}, opt)
+}
+
+func TestOptions_Visualization_Invalid(t *testing.T) {
+	for _, vo := range []VisualizationOptions{
+		{Mode: "bars"},
+		{Position: "left"},
+		{Width: -1},
+		{Color: "white[out]"},
+		{Background: "black:s=10x10"},
+	} {
+		_, err := vo.toEncoderOptions()
+		assert.Error(t, err, "%+v", vo)
+	}
+}
+
+func TestOptions_Texts(t *testing.T) {
+	fontsDir := path.Join(ResPath, "fonts")
+	req := &EncodingRequest{
+		JobId: "job1",
+		Title: "My podcast",
+		Options: EncodingOptions{Texts: []TextOverlay{
+			{Text: "{title} ({jobId})", Start: 0, End: 5},
+			{Text: "{timestamp}", Font: "DejaVuSans-Bold", FontSize: 20, Color: "yellow", Position: "top-right"},
+		}},
+	}
+	texts, err := makeTexts(req, fontsDir)
+	assert.NoError(t, err)
+	assert.Equal(t, []filtergraph.TextParams{
+		{Text: "My podcast (job1)", FontFile: path.Join(fontsDir, "DejaVuSans.ttf"), End: 5 * time.Second},
+		{Text: "{timestamp}", FontFile: path.Join(fontsDir, "DejaVuSans-Bold.ttf"), FontSize: 20, Color: "yellow", Position: filtergraph.TopRight},
+	}, texts)
+}
+
+func TestOptions_Texts_Invalid(t *testing.T) {
+	fontsDir := path.Join(ResPath, "fonts")
+	for _, to := range []TextOverlay{
+		{Text: "a", Position: "left"},
+		{Text: "a", Font: "../../etc/passwd"},
+		{Text: "a", Font: "Missing"},
+		{Text: "a", Color: "red:x=0"},
+		{Text: "a", Start: 5, End: 2},
+	} {
+		req := &EncodingRequest{Options: EncodingOptions{Texts: []TextOverlay{to}}}
+		_, err := makeTexts(req, fontsDir)
+		assert.Error(t, err, "%+v", to)
+	}
+}
+
+func TestOptions_Canvas(t *testing.T) {
+	canvas, err := (&CanvasOptions{Width: 1080, Height: 1920, Fit: "blurpad"}).toEncoderOptions()
+	assert.NoError(t, err)
+	assert.Equal(t, &filtergraph.Canvas{Width: 1080, Height: 1920, FrameRate: 25, Fit: filtergraph.BlurPad}, canvas)
+}
+
+func TestOptions_Canvas_Invalid(t *testing.T) {
+	for _, co := range []CanvasOptions{
+		{Width: 1080},
+		{Width: 1081, Height: 1920},
+		{Width: 8192, Height: 1920},
+		{Width: 1080, Height: 1920, FrameRate: 500},
+		{Width: 1080, Height: 1920, Fit: "stretch"},
+	} {
+		_, err := co.toEncoderOptions()
+		assert.Error(t, err, "%+v", co)
+	}
+}
+
+func TestOptions_Slideshow_Timestamps(t *testing.T) {
+	opt, err := (&SlideshowOptions{Timestamps: []float64{0, 5, 12.5}, Transition: "fade"}).toEncoderOptions(3)
+	assert.NoError(t, err)
+	assert.Equal(t, &encoder.SlideshowOptions{
+		Durations:  []time.Duration{5 * time.Second, 7500 * time.Millisecond},
+		Transition: "fade",
+	}, opt)
+}
+
+func TestOptions_Slideshow_Durations(t *testing.T) {
+	opt, err := (&SlideshowOptions{Durations: []float64{2, 3}, KenBurns: true}).toEncoderOptions(2)
+	assert.NoError(t, err)
+	assert.Equal(t, []time.Duration{2 * time.Second, 3 * time.Second}, opt.Durations)
+	assert.True(t, opt.KenBurns)
+}
+
+func TestOptions_Slideshow_Invalid(t *testing.T) {
+	for _, so := range []SlideshowOptions{
+		{Transition: "custom"},
+		{TransitionDuration: -1},
+		{Durations: []float64{1, 2}, Timestamps: []float64{0, 1}},
+		{Durations: []float64{1}},
+		{Durations: []float64{1, 0}},
+		{Timestamps: []float64{1, 2}},
+		{Timestamps: []float64{0, 0}},
+	} {
+		_, err := so.toEncoderOptions(2)
+		assert.Error(t, err, "%+v", so)
+	}
+}
+
+func TestOptions_Extras(t *testing.T) {
+	at := 12.5
+	eo := &EncodingOptions{Poster: &PosterOptions{At: &at}, Sprite: &SpriteOptions{Interval: 5, Width: 320, Height: 180}}
+	assert.NoError(t, eo.validateExtras())
+	assert.Equal(t, encoder.SpriteOptions{Interval: 5 * time.Second, Width: 320, Height: 180}, eo.Sprite.toEncoderOptions())
+}
+
+func TestOptions_Extras_Invalid(t *testing.T) {
+	at := -1.0
+	for _, eo := range []EncodingOptions{
+		{Poster: &PosterOptions{At: &at}},
+		{Sprite: &SpriteOptions{Interval: -5}},
+		{Sprite: &SpriteOptions{Width: 161, Height: 90}},
+	} {
+		assert.Error(t, eo.validateExtras(), "%+v", eo)
+	}
+}
+
+func TestOptions_Preview(t *testing.T) {
+	eo := &EncodingOptions{Preview: &PreviewOptions{Start: 30, Duration: 3, Format: "gif"}}
+	assert.NoError(t, eo.validateExtras())
+	assert.Equal(t,
+		encoder.PreviewOptions{Start: 30 * time.Second, Duration: 3 * time.Second, Format: encoder.PreviewGIF},
+		eo.Preview.toEncoderOptions())
+}
+
+func TestOptions_Preview_Invalid(t *testing.T) {
+	for _, po := range []PreviewOptions{
+		{Start: -1},
+		{Duration: 120},
+		{Width: 481},
+		{Format: "avi"},
+	} {
+		eo := EncodingOptions{Preview: &po}
+		assert.Error(t, eo.validateExtras(), "%+v", po)
+	}
+}
+
+func TestOptions_Quality(t *testing.T) {
+	// Without any profile, standard quality h264 is used
+	eo := &EncodingOptions{}
+	q, err := eo.toQualityOptions("")
+	assert.NoError(t, err)
+	assert.Equal(t, encoder.QualityOptions{}, q)
+	// The server default is used only if the request has no profile
+	q, err = eo.toQualityOptions("draft")
+	assert.NoError(t, err)
+	assert.Equal(t, encoder.QualityOptions{Profile: encoder.DraftQuality}, q)
+	eo = &EncodingOptions{Quality: "archive", Codec: "av1"}
+	q, err = eo.toQualityOptions("draft")
+	assert.NoError(t, err)
+	assert.Equal(t, encoder.QualityOptions{Profile: encoder.ArchiveQuality, Codec: encoder.AV1}, q)
+}
+
+func TestOptions_Quality_Invalid(t *testing.T) {
+	for _, eo := range []EncodingOptions{{Quality: "ultra"}, {Codec: "mpeg2"}} {
+		_, err := eo.toQualityOptions("")
+		assert.Error(t, err, "%+v", eo)
+	}
+	assert.True(t, IsQualityProfile("archive"))
+	assert.False(t, IsQualityProfile(""))
+}
+
+func TestOptions_AudioFormat(t *testing.T) {
+	req := &EncodingRequest{AudiosKeys: []string{"a"}, Options: EncodingOptions{Audio: &AudioFormatOptions{SampleRate: 48000, Channels: "mono"}}}
+	opt, err := req.toPresetOptions()
+	assert.NoError(t, err)
+	assert.Equal(t, filtergraph.AudioFormat{SampleRate: filtergraph.K48, SampleFormat: filtergraph.Fltp, ChannelLayout: filtergraph.Mono}, opt.AudioFormat)
+}
+
+func TestOptions_AudioFormat_Invalid(t *testing.T) {
+	for _, afo := range []AudioFormatOptions{{SampleRate: 12345}, {SampleFormat: "u8"}, {Channels: "7.1"}} {
+		_, err := afo.toEncoderOptions()
+		assert.Error(t, err, "%+v", afo)
+	}
+}
+
+func TestOptions_CustomFilters(t *testing.T) {
+	eo := EncodingOptions{Filters: []FilterOptions{
+		{Name: "aecho", Stream: "audio", Options: []filtergraph.RawOption{{Key: "in_gain", Value: "0.8"}, {Value: "0.9"}}},
+		{Name: "colorchannelmixer", Stream: "video", Options: []filtergraph.RawOption{{Key: "rr", Value: "0.5"}}},
+	}}
+	audio, video, err := eo.toCustomFilters([]string{"aecho", "colorchannelmixer"})
+	assert.NoError(t, err)
+	assert.Equal(t, []encoder.CustomFilter{{Name: "aecho", Options: eo.Filters[0].Options}}, audio)
+	assert.Equal(t, []encoder.CustomFilter{{Name: "colorchannelmixer", Options: eo.Filters[1].Options}}, video)
+
+	// No custom filter is fine, whatever the allowlist
+	eo = EncodingOptions{}
+	audio, video, err = eo.toCustomFilters(nil)
+	assert.NoError(t, err)
+	assert.Empty(t, audio)
+	assert.Empty(t, video)
+}
+
+func TestOptions_CustomFilters_Invalid(t *testing.T) {
+	allowed := []string{"aecho"}
+	for _, f := range []FilterOptions{
+		// Not in the allowlist
+		{Name: "movie", Stream: "video"},
+		{Name: "aecho", Stream: "subtitles"},
+		{Name: "aecho", Stream: "audio", Options: []filtergraph.RawOption{{Key: "in_gain[x]", Value: "1"}}},
+	} {
+		eo := EncodingOptions{Filters: []FilterOptions{f}}
+		_, _, err := eo.toCustomFilters(allowed)
+		assert.Error(t, err, "%+v", f)
+	}
+	// Nothing is allowed by default
+	eo := EncodingOptions{Filters: []FilterOptions{{Name: "aecho", Stream: "audio"}}}
+	_, _, err := eo.toCustomFilters(nil)
+	assert.Error(t, err)
+	// Too many filters
+	eo = EncodingOptions{Filters: make([]FilterOptions, maxCustomFilters+1)}
+	_, _, err = eo.toCustomFilters(allowed)
+	assert.Error(t, err)
+}
+
+// Ranges are reported in seconds, not as Duration nanoseconds
+func TestOptions_TimeRanges(t *testing.T) {
+	ranges := toTimeRanges([]filtergraph.TimeRange{{Start: 1500 * time.Millisecond, End: 3 * time.Second}})
+	assert.Equal(t, []TimeRange{{Start: 1.5, End: 3}}, ranges)
+	res, err := json.Marshal(EncodingResult{Cuts: ranges})
+	assert.Nil(t, err)
+	assert.JSONEq(t, `{"cuts":[{"start":1.5,"end":3}]}`, string(res))
+}
@@ -0,0 +1,137 @@
+package encoder
+
+import (
+	"bytes"
+	"context"
+	console_parser "encode-box/pkg/encoder/console-parser"
+	"encode-box/pkg/encoder/filtergraph"
+	"fmt"
+	"os/exec"
+	"time"
+)
+
+// MeasureLoudness Run a loudnorm analysis pass on the main audio track built from audioPaths.
+// The measured values can then be used to perform a linear (second pass) normalization
+func MeasureLoudness(ctx *context.Context, audioPaths []string, opt *PresetOptions) (*filtergraph.LoudnormMeasurement, error) {
+	opt = withDefaults(opt)
+	builder := Builder{}
+	// The measure must be made on the audio track as it will be normalized
+	graphRoot := applyCustomFilters(addAudioTracks(&builder, audioPaths, opt), opt.AudioFilters)
+	graphRoot = filtergraph.NewLoudnormAnalysisFilter(graphRoot, opt.Loudness.targets())
+
+	output, err := runAnalysis(ctx, &builder, graphRoot)
+	if err != nil {
+		return nil, err
+	}
+	stats, err := console_parser.ParseLoudnormStats(output)
+	if err != nil {
+		return nil, err
+	}
+	return &filtergraph.LoudnormMeasurement{
+		I:      stats.InputI,
+		TP:     stats.InputTP,
+		LRA:    stats.InputLRA,
+		Thresh: stats.InputThresh,
+		Offset: stats.TargetOffset,
+	}, nil
+}
+
+// SilenceDetection Silences found in the main audio track
+type SilenceDetection struct {
+	// All detected silences
+	Silences []filtergraph.TimeRange
+	// Duration of the analysed audio track
+	Duration time.Duration
+}
+
+// Tolerance used to decide if a silence touches an edge of the audio track
+const edgeTolerance = 100 * time.Millisecond
+
+// DetectSilences Run a silencedetect analysis pass on the main audio track built from audioPaths
+func DetectSilences(ctx *context.Context, audioPaths []string, opt *PresetOptions, silence SilenceOptions) (*SilenceDetection, error) {
+	opt = withDefaults(opt)
+	if silence.Threshold == 0 {
+		silence.Threshold = -50
+	}
+	if silence.MinDuration == 0 {
+		silence.MinDuration = 2 * time.Second
+	}
+	builder := Builder{}
+	graphRoot := addAudioTracks(&builder, audioPaths, opt)
+	graphRoot = filtergraph.NewSilenceDetectFilter(graphRoot, silence.Threshold, silence.MinDuration)
+
+	output, err := runAnalysis(ctx, &builder, graphRoot)
+	if err != nil {
+		return nil, err
+	}
+	silences, duration, err := console_parser.ParseSilences(output)
+	if err != nil {
+		return nil, err
+	}
+	detection := &SilenceDetection{Duration: duration}
+	for _, s := range silences {
+		detection.Silences = append(detection.Silences, filtergraph.TimeRange{Start: s.Start, End: s.End})
+	}
+	return detection, nil
+}
+
+// Cuts Parts of the audio track to remove according to the silence options.
+// Leading and trailing silences are entirely removed if trimEdges is set, and
+// internal silences longer than maxSilence are shortened to maxSilence
+func (sd *SilenceDetection) Cuts(trimEdges bool, maxSilence time.Duration) []filtergraph.TimeRange {
+	var cuts []filtergraph.TimeRange
+	for _, s := range sd.Silences {
+		isEdge := s.Start <= edgeTolerance || s.End >= sd.Duration-edgeTolerance
+		if isEdge {
+			if trimEdges {
+				cuts = append(cuts, s)
+			}
+			continue
+		}
+		if maxSilence > 0 && s.Duration() > maxSilence {
+			// Keep half of the allowed silence on each side, so that the cut is not noticeable
+			cuts = append(cuts, filtergraph.TimeRange{Start: s.Start + maxSilence/2, End: s.End - maxSilence/2})
+		}
+	}
+	return cuts
+}
+
+// CutDuration Total duration removed by the given cuts
+func CutDuration(cuts []filtergraph.TimeRange) time.Duration {
+	var total time.Duration
+	for _, c := range cuts {
+		total += c.Duration()
+	}
+	return total
+}
+
+// Run the builder graph up to graphRoot without producing any file, and return the whole FFmpeg output.
+// This is used to run analysis filters before the real encoding
+func runAnalysis(ctx *context.Context, builder *Builder, graphRoot filtergraph.Filter) (string, error) {
+	builder.
+		SetFilterGraph(graphRoot).
+		MapStream(graphRoot).
+		// Discard the result, we're only interested in the logs
+		AddOutputOption("-f", "null").
+		SetOutput("-")
+	output, err := runFFmpeg(ctx, builder)
+	if err != nil {
+		return "", fmt.Errorf("analysis pass failed : %w", err)
+	}
+	return output, nil
+}
+
+// Run the builder command until completion, without any progress tracking, and return the whole FFmpeg output
+func runFFmpeg(ctx *context.Context, builder *Builder) (string, error) {
+	args, err := builder.getFFmpegArgs()
+	if err != nil {
+		return "", err
+	}
+	cmd := exec.CommandContext(*ctx, "ffmpeg", args...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return stderr.String(), err
+	}
+	return stderr.String(), nil
+}
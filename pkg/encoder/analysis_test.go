@@ -0,0 +1,37 @@
+package encoder
+
+import (
+	"encode-box/pkg/encoder/filtergraph"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+var detection = SilenceDetection{
+	Silences: []filtergraph.TimeRange{
+		{Start: 0, End: 3 * time.Second},
+		{Start: 10 * time.Second, End: 11 * time.Second},
+		{Start: 20 * time.Second, End: 30 * time.Second},
+		{Start: 55 * time.Second, End: 60 * time.Second},
+	},
+	Duration: 60 * time.Second,
+}
+
+func TestSilenceDetection_Cuts_TrimEdges(t *testing.T) {
+	cuts := detection.Cuts(true, 0)
+	assert.Equal(t, []filtergraph.TimeRange{
+		{Start: 0, End: 3 * time.Second},
+		{Start: 55 * time.Second, End: 60 * time.Second},
+	}, cuts)
+	assert.Equal(t, 8*time.Second, CutDuration(cuts))
+}
+
+func TestSilenceDetection_Cuts_MaxSilence(t *testing.T) {
+	cuts := detection.Cuts(false, 2*time.Second)
+	// Only the long internal silence is shortened, keeping 1s on each side
+	assert.Equal(t, []filtergraph.TimeRange{{Start: 21 * time.Second, End: 29 * time.Second}}, cuts)
+}
+
+func TestSilenceDetection_Cuts_None(t *testing.T) {
+	assert.Empty(t, detection.Cuts(false, 0))
+}
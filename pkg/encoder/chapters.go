@@ -0,0 +1,95 @@
+package encoder
+
+import (
+	"encode-box/pkg/encoder/filtergraph"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Chapter A navigable section of the output
+type Chapter struct {
+	Title string
+	Start time.Duration
+	End   time.Duration
+}
+
+// AudioPartsChapters Return a chapter for each audio part, starting when the part starts
+// once all parts are concatenated. Parts durations are exact, so that chapters match the parts boundaries.
+// Ends are left for the caller to define
+func AudioPartsChapters(audioPaths []string) ([]Chapter, error) {
+	var chapters []Chapter
+	var start time.Duration
+	for i, aPath := range audioPaths {
+		dur, err := GetDuration(aPath)
+		if err != nil {
+			return nil, fmt.Errorf("could not get duration of audio part %d : %w", i, err)
+		}
+		chapters = append(chapters, Chapter{Title: fmt.Sprintf("Part %d", i+1), Start: start})
+		start += dur
+	}
+	return chapters, nil
+}
+
+// ShiftForCuts Move chapters starts to take into account the parts removed before them.
+// A chapter starting in a removed part will start right after the cut
+func ShiftForCuts(chapters []Chapter, cuts []filtergraph.TimeRange) []Chapter {
+	shifted := make([]Chapter, len(chapters))
+	for i, c := range chapters {
+		shifted[i] = Chapter{Title: c.Title, Start: shiftForCuts(c.Start, cuts), End: c.End}
+	}
+	return shifted
+}
+
+// Return the timestamp t once the cuts are removed. A timestamp in a removed part is moved right after the cut
+func shiftForCuts(t time.Duration, cuts []filtergraph.TimeRange) time.Duration {
+	var removed time.Duration
+	for _, cut := range cuts {
+		if cut.End <= t {
+			removed += cut.Duration()
+		} else if cut.Start < t {
+			removed += t - cut.Start
+		}
+	}
+	return t - removed
+}
+
+// WriteFFMetadata Write all chapters into path, using the FFMETADATA format
+// Documentation : https://ffmpeg.org/ffmpeg-formats.html#Metadata-1
+func WriteFFMetadata(path string, chapters []Chapter) error {
+	ss := strings.Builder{}
+	ss.WriteString(";FFMETADATA1\n")
+	for _, c := range chapters {
+		ss.WriteString("[CHAPTER]\n")
+		ss.WriteString("TIMEBASE=1/1000\n")
+		ss.WriteString(fmt.Sprintf("START=%d\n", c.Start.Milliseconds()))
+		ss.WriteString(fmt.Sprintf("END=%d\n", c.End.Milliseconds()))
+		ss.WriteString(fmt.Sprintf("title=%s\n", escapeFFMetadata(c.Title)))
+	}
+	return os.WriteFile(path, []byte(ss.String()), 0600)
+}
+
+// Write the chapters as an FFMETADATA file next to the output, and use them in the output
+func addChapters(builder *Builder, chapters []Chapter) error {
+	if len(chapters) == 0 {
+		return nil
+	}
+	path := builder.output + ".ffmetadata"
+	if err := WriteFFMetadata(path, chapters); err != nil {
+		return fmt.Errorf("could not write chapters : %w", err)
+	}
+	index := len(builder.inputs)
+	builder.AddInput(&FileInput{Path: path, Format: "ffmetadata"})
+	builder.
+		AddOutputOption("-map_metadata", strconv.Itoa(index)).
+		AddOutputOption("-map_chapters", strconv.Itoa(index))
+	return nil
+}
+
+// Special characters of the FFMETADATA format must be escaped with a backslash
+func escapeFFMetadata(value string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, "=", `\=`, ";", `\;`, "#", `\#`, "\n", "\\\n")
+	return replacer.Replace(value)
+}
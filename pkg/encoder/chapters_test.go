@@ -0,0 +1,61 @@
+package encoder
+
+import (
+	"encode-box/pkg/encoder/filtergraph"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestChapters_WriteFFMetadata(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "chapters")
+	err := WriteFFMetadata(path, []Chapter{
+		{Title: "Intro", Start: 0, End: 90 * time.Second},
+		{Title: "Q&A; part=2", Start: 90 * time.Second, End: 120500 * time.Millisecond},
+	})
+	assert.NoError(t, err)
+	content, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, `;FFMETADATA1
+[CHAPTER]
+TIMEBASE=1/1000
+START=0
+END=90000
+title=Intro
+[CHAPTER]
+TIMEBASE=1/1000
+START=90000
+END=120500
+title=Q&A\; part\=2
+`, string(content))
+}
+
+func TestChapters_ShiftForCuts(t *testing.T) {
+	chapters := []Chapter{
+		{Title: "1", Start: 0},
+		{Title: "2", Start: 10 * time.Second},
+		{Title: "3", Start: 20 * time.Second},
+	}
+	cuts := []filtergraph.TimeRange{
+		{Start: 0, End: 2 * time.Second},
+		// Chapter 3 starts in the middle of this cut
+		{Start: 18 * time.Second, End: 22 * time.Second},
+	}
+	shifted := ShiftForCuts(chapters, cuts)
+	assert.Equal(t, time.Duration(0), shifted[0].Start)
+	assert.Equal(t, 8*time.Second, shifted[1].Start)
+	assert.Equal(t, 16*time.Second, shifted[2].Start)
+}
+
+func TestChapters_AddChapters(t *testing.T) {
+	builder := &Builder{}
+	builder.AddInput(&FileInput{Path: "a"}).SetOutput(filepath.Join(t.TempDir(), "out.mp4"))
+	err := addChapters(builder, []Chapter{{Title: "1", End: time.Second}})
+	assert.NoError(t, err)
+	assert.Len(t, builder.inputs, 2)
+	assert.Equal(t, "ffmetadata", builder.inputs[1].Format)
+	assert.Equal(t, []string{"-map_metadata", "1", "-map_chapters", "1"}, builder.outputOptions)
+}
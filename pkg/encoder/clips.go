@@ -0,0 +1,116 @@
+package encoder
+
+import (
+	"encode-box/pkg/encoder/filtergraph"
+	"fmt"
+	"time"
+)
+
+// Return true if clips are stitched around the main content
+func (o *PresetOptions) hasClips() bool {
+	return o.Intro != "" || o.Outro != ""
+}
+
+// Stitch the intro and outro clips around the main content, the clips being normalized to the canvas.
+// The main video must already fit in the canvas.
+// If the main video is infinite (looped image, color source), the main content is bounded by the duration
+// of boundingAudio, the main audio tracks. Otherwise, boundingAudio must be nil.
+// Return the resulting audio and video tracks, and the time at which the main content starts in the output
+func addIntroOutro(builder *Builder, audioRoot filtergraph.Filter, videoRoot filtergraph.Filter, opt *PresetOptions, boundingAudio []string) (filtergraph.Filter, filtergraph.Filter, time.Duration, error) {
+	if !opt.hasClips() {
+		return audioRoot, videoRoot, 0, nil
+	}
+	if boundingAudio != nil {
+		duration, err := mainAudioDuration(boundingAudio, opt)
+		if err != nil {
+			return nil, nil, 0, fmt.Errorf("could not get main content duration : %w", err)
+		}
+		videoRoot = filtergraph.NewTrimFilter(videoRoot, duration)
+		audioRoot = filtergraph.NewAudioTrimFilter(audioRoot, duration)
+	}
+
+	var segments []filtergraph.Segment
+	var offset time.Duration
+	if opt.Intro != "" {
+		var err error
+		if offset, err = GetDuration(opt.Intro); err != nil {
+			return nil, nil, 0, fmt.Errorf("could not get intro duration : %w", err)
+		}
+		segments = append(segments, addClip(builder, opt.Intro, opt))
+	}
+	// The main video already fits in the canvas
+	segments = append(segments, filtergraph.Segment{
+		Video: videoRoot,
+		Audio: filtergraph.NewAudioResampleFilter(audioRoot, opt.AudioFormat),
+	})
+	if opt.Outro != "" {
+		segments = append(segments, addClip(builder, opt.Outro, opt))
+	}
+	concat := filtergraph.NewConcatFilter(segments...)
+	return concat.Audio(), concat, offset, nil
+}
+
+// Add the clip as an input of the builder, and return it normalized.
+// A clip without audio stream is played along with silence, as each segment must have both streams
+func addClip(builder *Builder, path string, opt *PresetOptions) filtergraph.Segment {
+	index := len(builder.inputs)
+	builder.AddInput(&FileInput{Path: path})
+	var audio filtergraph.Filter = filtergraph.NewInput(fmt.Sprintf("%d:a", index))
+	// A clip that can't be probed is assumed to have an audio stream, FFmpeg will report the error if it doesn't
+	if info, err := Probe(path); err == nil && !info.HasAudio() {
+		audio = addSilence(builder, info.Format.Duration)
+	}
+	return normalizeSegment(
+		filtergraph.NewInput(fmt.Sprintf("%d:v", index)),
+		audio,
+		opt.canvas(),
+		opt.AudioFormat)
+}
+
+// Add a silent audio input lasting duration, and return it
+func addSilence(builder *Builder, duration time.Duration) filtergraph.Filter {
+	index := len(builder.inputs)
+	builder.AddInput(&FileInput{
+		Path:    "anullsrc",
+		Format:  "lavfi",
+		Options: []string{"-t", fmt.Sprintf("%.3f", duration.Seconds())},
+	})
+	return filtergraph.NewInput(fmt.Sprintf("%d:a", index))
+}
+
+// All segments must share the same video and audio parameters to be concatenated
+func normalizeSegment(video filtergraph.Filter, audio filtergraph.Filter, canvas filtergraph.Canvas, format filtergraph.AudioFormat) filtergraph.Segment {
+	return filtergraph.Segment{
+		Video: filtergraph.NewVideoNormalizeFilter(video, canvas),
+		Audio: filtergraph.NewAudioResampleFilter(audio, format),
+	}
+}
+
+// Duration of the main audio track built from audioPaths, once combined and cut
+func mainAudioDuration(audioPaths []string, opt *PresetOptions) (time.Duration, error) {
+	var total time.Duration
+	for _, aPath := range audioPaths {
+		duration, err := GetDuration(aPath)
+		if err != nil {
+			return 0, err
+		}
+		if opt.AudioLayout == MixLayout {
+			// Tracks are played simultaneously, the longest one sets the duration
+			if duration > total {
+				total = duration
+			}
+		} else {
+			total += duration
+		}
+	}
+	return total - CutDuration(opt.Cuts), nil
+}
+
+// Return the chapters delayed by offset
+func delayChapters(chapters []Chapter, offset time.Duration) []Chapter {
+	delayed := make([]Chapter, len(chapters))
+	for i, c := range chapters {
+		delayed[i] = Chapter{Title: c.Title, Start: c.Start + offset, End: c.End + offset}
+	}
+	return delayed
+}
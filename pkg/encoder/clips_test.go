@@ -0,0 +1,37 @@
+package encoder
+
+import (
+	"encode-box/pkg/encoder/filtergraph"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestClips_NoClips(t *testing.T) {
+	builder := Builder{}
+	audio, video := filtergraph.NewInput("1"), filtergraph.NewInput("0:v")
+	a, v, offset, err := addIntroOutro(&builder, audio, video, &PresetOptions{}, []string{"/tmp/audio.m4a"})
+	assert.NoError(t, err)
+	assert.Equal(t, audio, a)
+	assert.Equal(t, video, v)
+	assert.Equal(t, time.Duration(0), offset)
+	assert.Empty(t, builder.inputs)
+}
+
+func TestClips_AddClip(t *testing.T) {
+	builder := Builder{}
+	builder.AddInput(&FileInput{Path: "/tmp/video.mp4"})
+	segment := addClip(&builder, "/tmp/outro.mp4", withDefaults(nil))
+	assert.Len(t, builder.inputs, 2)
+	assert.Contains(t, segment.Video.Build(), "[1:v]scale=w=1280:h=720")
+	assert.Contains(t, segment.Audio.Build(), "[1:a]aformat=sample_fmts=fltp:sample_rates=44100:channel_layouts=stereo")
+	// The clip audio must match the main audio format
+	mono := &PresetOptions{AudioFormat: filtergraph.AudioFormat{SampleRate: filtergraph.K48, ChannelLayout: filtergraph.Mono}}
+	segment = addClip(&builder, "/tmp/intro.mp4", mono)
+	assert.Contains(t, segment.Audio.Build(), "[2:a]aformat=sample_fmts=fltp:sample_rates=48000:channel_layouts=mono")
+}
+
+func TestClips_DelayChapters(t *testing.T) {
+	chapters := delayChapters([]Chapter{{Title: "1", Start: 0, End: 10 * time.Second}}, 5*time.Second)
+	assert.Equal(t, []Chapter{{Title: "1", Start: 5 * time.Second, End: 15 * time.Second}}, chapters)
+}
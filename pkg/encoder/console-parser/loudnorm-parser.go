@@ -0,0 +1,76 @@
+package console_parser
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// LoudnormStats Measurements printed by the loudnorm filter when used with print_format=json
+type LoudnormStats struct {
+	InputI            float64
+	InputTP           float64
+	InputLRA          float64
+	InputThresh       float64
+	OutputI           float64
+	OutputTP          float64
+	OutputLRA         float64
+	OutputThresh      float64
+	NormalizationType string
+	TargetOffset      float64
+}
+
+// Loudnorm prints every value as a string
+type rawLoudnormStats struct {
+	InputI            string `json:"input_i"`
+	InputTP           string `json:"input_tp"`
+	InputLRA          string `json:"input_lra"`
+	InputThresh       string `json:"input_thresh"`
+	OutputI           string `json:"output_i"`
+	OutputTP          string `json:"output_tp"`
+	OutputLRA         string `json:"output_lra"`
+	OutputThresh      string `json:"output_thresh"`
+	NormalizationType string `json:"normalization_type"`
+	TargetOffset      string `json:"target_offset"`
+}
+
+// ParseLoudnormStats Extract the loudnorm JSON stats from a complete FFmpeg output
+func ParseLoudnormStats(output string) (*LoudnormStats, error) {
+	// The JSON block is printed at the very end of the analysis, after the filter name
+	start := strings.LastIndex(output, "{")
+	end := strings.LastIndex(output, "}")
+	if start == -1 || end < start {
+		return nil, fmt.Errorf("no loudnorm stats found in output")
+	}
+	var raw rawLoudnormStats
+	if err := json.Unmarshal([]byte(output[start:end+1]), &raw); err != nil {
+		return nil, fmt.Errorf("invalid loudnorm stats : %w", err)
+	}
+
+	stats := &LoudnormStats{NormalizationType: raw.NormalizationType}
+	fields := []struct {
+		raw    string
+		parsed *float64
+	}{
+		{raw.InputI, &stats.InputI},
+		{raw.InputTP, &stats.InputTP},
+		{raw.InputLRA, &stats.InputLRA},
+		{raw.InputThresh, &stats.InputThresh},
+		{raw.OutputI, &stats.OutputI},
+		{raw.OutputTP, &stats.OutputTP},
+		{raw.OutputLRA, &stats.OutputLRA},
+		{raw.OutputThresh, &stats.OutputThresh},
+		{raw.TargetOffset, &stats.TargetOffset},
+	}
+	for _, f := range fields {
+		// A silent input will be measured as "-inf", which can't be used in a second pass
+		n, err := strconv.ParseFloat(strings.TrimSpace(f.raw), 64)
+		if err != nil || math.IsInf(n, 0) {
+			return nil, fmt.Errorf("invalid loudnorm value \"%s\"", f.raw)
+		}
+		*f.parsed = n
+	}
+	return stats, nil
+}
@@ -0,0 +1,46 @@
+package console_parser
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+const loudnormOutput = `size=N/A time=00:00:03.01 bitrate=N/A speed= 104x
+[Parsed_loudnorm_1 @ 0x55d0c5e3f3c0]
+{
+	"input_i" : "-27.61",
+	"input_tp" : "-4.47",
+	"input_lra" : "18.06",
+	"input_thresh" : "-39.20",
+	"output_i" : "-16.58",
+	"output_tp" : "-1.50",
+	"output_lra" : "14.78",
+	"output_thresh" : "-27.71",
+	"normalization_type" : "dynamic",
+	"target_offset" : "0.58"
+}
+`
+
+func TestConsoleParser_ParseLoudnormStats(t *testing.T) {
+	stats, err := ParseLoudnormStats(loudnormOutput)
+	assert.NoError(t, err)
+	assert.Equal(t, -27.61, stats.InputI)
+	assert.Equal(t, -4.47, stats.InputTP)
+	assert.Equal(t, 18.06, stats.InputLRA)
+	assert.Equal(t, -39.2, stats.InputThresh)
+	assert.Equal(t, 0.58, stats.TargetOffset)
+	assert.Equal(t, "dynamic", stats.NormalizationType)
+}
+
+func TestConsoleParser_ParseLoudnormStats_NoStats(t *testing.T) {
+	_, err := ParseLoudnormStats("frame= 2349 fps=335 q=28.0")
+	assert.Error(t, err)
+}
+
+// A silent input is measured as -inf, which cannot be used in a second pass
+func TestConsoleParser_ParseLoudnormStats_Silence(t *testing.T) {
+	_, err := ParseLoudnormStats(`{"input_i" : "-inf", "input_tp" : "-inf", "input_lra" : "0.00", "input_thresh" : "-70.00",
+	"output_i" : "-inf", "output_tp" : "-inf", "output_lra" : "0.00", "output_thresh" : "-70.00",
+	"normalization_type" : "dynamic", "target_offset" : "inf"}`)
+	assert.Error(t, err)
+}
@@ -0,0 +1,69 @@
+package console_parser
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+// Silence A silence detected by the silencedetect filter
+type Silence struct {
+	Start time.Duration
+	End   time.Duration
+}
+
+var (
+	silenceStartRegex = regexp.MustCompile(`silence_start: (-?[\d.]+)`)
+	silenceEndRegex   = regexp.MustCompile(`silence_end: (-?[\d.]+)`)
+	progressTimeRegex = regexp.MustCompile(`time=(\d+):(\d{2}):(\d{2}(?:\.\d+)?)`)
+)
+
+// ParseSilences Extract all silences detected by silencedetect from a complete FFmpeg output.
+// The total duration of the analysed stream is returned alongside, as a silence lasting until the
+// end of the stream may not be closed by silencedetect
+func ParseSilences(output string) ([]Silence, time.Duration, error) {
+	duration, err := parseLastProgressTime(output)
+	if err != nil {
+		return nil, 0, err
+	}
+	starts := silenceStartRegex.FindAllStringSubmatch(output, -1)
+	ends := silenceEndRegex.FindAllStringSubmatch(output, -1)
+	if len(ends) > len(starts) {
+		return nil, 0, fmt.Errorf("invalid silencedetect output, %d silence ends for %d starts", len(ends), len(starts))
+	}
+	var silences []Silence
+	for i, start := range starts {
+		s := Silence{Start: parseSeconds(start[1]), End: duration}
+		if i < len(ends) {
+			s.End = parseSeconds(ends[i][1])
+		}
+		// Silencedetect can report slightly negative timestamps for a silence starting the stream
+		if s.Start < 0 {
+			s.Start = 0
+		}
+		silences = append(silences, s)
+	}
+	return silences, duration, nil
+}
+
+// Return the processed time of the last progress line
+func parseLastProgressTime(output string) (time.Duration, error) {
+	matches := progressTimeRegex.FindAllStringSubmatch(output, -1)
+	if len(matches) == 0 {
+		return 0, fmt.Errorf("no progress time found in output")
+	}
+	last := matches[len(matches)-1]
+	hours, _ := strconv.Atoi(last[1])
+	minutes, _ := strconv.Atoi(last[2])
+	return time.Duration(hours)*time.Hour + time.Duration(minutes)*time.Minute + parseSeconds(last[3]), nil
+}
+
+// Parse a "12.345" seconds string into a Duration. Invalid strings are parsed as 0
+func parseSeconds(raw string) time.Duration {
+	secs, err := strconv.ParseFloat(raw, 64)
+	if err != nil {
+		return 0
+	}
+	return time.Duration(secs * float64(time.Second))
+}
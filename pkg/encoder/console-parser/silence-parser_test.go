@@ -0,0 +1,40 @@
+package console_parser
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+const silenceOutput = `[silencedetect @ 0x5581f7e0c940] silence_start: -0.00133
+[silencedetect @ 0x5581f7e0c940] silence_end: 2.5 | silence_duration: 2.50133
+[silencedetect @ 0x5581f7e0c940] silence_start: 10.25
+[silencedetect @ 0x5581f7e0c940] silence_end: 14 | silence_duration: 3.75
+size=N/A time=00:00:15.02 bitrate=N/A speed= 389x
+[silencedetect @ 0x5581f7e0c940] silence_start: 18
+size=N/A time=00:00:20.50 bitrate=N/A speed= 390x`
+
+func TestConsoleParser_ParseSilences(t *testing.T) {
+	silences, duration, err := ParseSilences(silenceOutput)
+	assert.NoError(t, err)
+	assert.Equal(t, 20*time.Second+500*time.Millisecond, duration)
+	assert.Equal(t, []Silence{
+		// Negative start clamped to 0
+		{Start: 0, End: 2500 * time.Millisecond},
+		{Start: 10250 * time.Millisecond, End: 14 * time.Second},
+		// The last silence lasts until the end of the stream
+		{Start: 18 * time.Second, End: 20*time.Second + 500*time.Millisecond},
+	}, silences)
+}
+
+func TestConsoleParser_ParseSilences_NoSilence(t *testing.T) {
+	silences, duration, err := ParseSilences("size=N/A time=01:02:03.50 bitrate=N/A speed= 389x")
+	assert.NoError(t, err)
+	assert.Empty(t, silences)
+	assert.Equal(t, time.Hour+2*time.Minute+3500*time.Millisecond, duration)
+}
+
+func TestConsoleParser_ParseSilences_NoProgress(t *testing.T) {
+	_, _, err := ParseSilences("shiny!")
+	assert.Error(t, err)
+}
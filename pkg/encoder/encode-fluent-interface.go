@@ -4,7 +4,6 @@ import (
 	"context"
 	"encode-box/pkg/encoder/filtergraph"
 	"fmt"
-	"strings"
 )
 
 type Builder struct {
@@ -12,12 +11,14 @@ type Builder struct {
 	inputs []*FileInput
 	// All general options on the input
 	inputOptions []string
-	// All options on the output file
+	// All options on the output file, one argument per item
 	outputOptions []string
 	// Output file name
 	output string
-	// Root of the filter graph to be used
-	filterGraph filtergraph.Filter
+	// Roots of the filter graph to be used. Each root is an independent output of the graph
+	filterGraphs []filtergraph.Filter
+	// Streams to write into the output, in order. Filtergraph labels are only known once the graph is built
+	streamMaps []filtergraph.Filter
 }
 
 // AddInput Add a new input to the encoder
@@ -26,9 +27,10 @@ func (eb *Builder) AddInput(input *FileInput) *Builder {
 	return eb
 }
 
-// AddOutputOption Add a new output option to the encoder
-func (eb *Builder) AddOutputOption(opt string) *Builder {
-	eb.outputOptions = append(eb.outputOptions, opt)
+// AddOutputOption Add a new output option to the encoder : its name, followed by its value if any.
+// Values are passed as is to FFmpeg, they don't need any quoting
+func (eb *Builder) AddOutputOption(args ...string) *Builder {
+	eb.outputOptions = append(eb.outputOptions, args...)
 	return eb
 }
 
@@ -40,37 +42,66 @@ func (eb *Builder) SetOutput(path string) *Builder {
 
 // SetFilterGraph Set the complex filters to be used
 func (eb *Builder) SetFilterGraph(graph filtergraph.Filter) *Builder {
-	eb.filterGraph = graph
+	eb.filterGraphs = []filtergraph.Filter{graph}
 	return eb
 }
 
-// Collapses the whole builder into a command-line string
-func (eb *Builder) getFFmpegCmd() string {
-	// ffmpeg (-i [inputs])* [inputOptions] [filters] [outputOptions] [output_name]
-	ss := strings.Builder{}
-	ss.WriteString("ffmpeg")
+// AddFilterGraph Add another independent output to the complex filters, for example
+// a video chain alongside an audio chain
+func (eb *Builder) AddFilterGraph(graph filtergraph.Filter) *Builder {
+	eb.filterGraphs = append(eb.filterGraphs, graph)
+	return eb
+}
+
+// MapStream Write the given stream into the output, either an input stream or a filtergraph output
+func (eb *Builder) MapStream(stream filtergraph.Filter) *Builder {
+	eb.streamMaps = append(eb.streamMaps, stream)
+	return eb
+}
+
+// Collapses the whole builder into the FFmpeg arguments, without the "ffmpeg" binary itself.
+// Each argument is passed as is to FFmpeg, no quoting is required
+func (eb *Builder) getFFmpegArgs() ([]string, error) {
+	// ffmpeg (-i [inputs])* [filters] [maps] [outputOptions] [output_name]
+	var args []string
 
 	// Inputs
 	for _, input := range eb.inputs {
-		ss.WriteString(fmt.Sprintf(" %s", input.String()))
+		args = append(args, input.Args()...)
 	}
 
 	// Filters
-	if eb.filterGraph != nil {
-		fGraph := eb.filterGraph.Build()
-		// Remove the last ";" in the last step of the filtergraph. That's how FFmpeg knows it's complete
-		ss.WriteString(fmt.Sprintf(` -filter_complex "%s"`, strings.TrimSuffix(fGraph, ";")))
+	var graph *filtergraph.Graph
+	if len(eb.filterGraphs) > 0 {
+		graph = filtergraph.NewGraph(len(eb.inputs), eb.filterGraphs...)
+		fGraph, err := graph.Build()
+		if err != nil {
+			return nil, fmt.Errorf("invalid filtergraph : %w", err)
+		}
+		// Streams mapped straight from the inputs don't need any filter
+		if fGraph != "" {
+			args = append(args, "-filter_complex", fGraph)
+		}
 	}
 
-	// Output options
-	for _, outputOpt := range eb.outputOptions {
-		ss.WriteString(fmt.Sprintf(" %s", outputOpt))
+	// Mapped streams, before any other output option so that they come first in the output
+	for _, stream := range eb.streamMaps {
+		args = append(args, "-map", mapStream(graph, stream))
 	}
 
+	// Output options
+	args = append(args, eb.outputOptions...)
+
 	// Output name
-	ss.WriteString(fmt.Sprintf(` %s`, eb.output))
+	return append(args, eb.output), nil
+}
 
-	return ss.String()
+// Return the -map value selecting the given stream, either an input stream or an output of the graph
+func mapStream(graph *filtergraph.Graph, stream filtergraph.Filter) string {
+	if _, isInput := stream.(*filtergraph.InputFilter); isInput || graph == nil {
+		return stream.Id()
+	}
+	return fmt.Sprintf("[%s]", graph.Label(stream))
 }
 
 // Build Return a new initialized encoder ready to be started
@@ -81,7 +112,11 @@ func (eb *Builder) Build(ctx *context.Context) (*Encoder, error) {
 	if eb.output == "" {
 		return nil, fmt.Errorf("no output file Path specified")
 	}
-	return NewEncoder(ctx, eb.getFFmpegCmd()), nil
+	args, err := eb.getFFmpegArgs()
+	if err != nil {
+		return nil, err
+	}
+	return NewEncoder(ctx, args), nil
 }
 
 // FileInput Any valid -i input
@@ -90,22 +125,16 @@ type FileInput struct {
 	Path string
 	// Format of the Path to input
 	Format string
-	// Specific options to be applied to this input
+	// Specific options to be applied to this input, one argument per item : {"-loop", "1"}
 	Options []string
 }
 
-// Convert the input into a FFMPEG compatible cmd
-func (ei *FileInput) String() string {
-	ss := strings.Builder{}
-
-	// Options
-	if len(ei.Options) != 0 {
-		ss.WriteString(fmt.Sprintf("%s ", strings.Join(ei.Options, " ")))
-	}
+// Convert the input into FFMPEG arguments
+func (ei *FileInput) Args() []string {
+	args := append([]string{}, ei.Options...)
 	// Only specify Format if explicitly specified
 	if ei.Format != "" {
-		ss.WriteString(fmt.Sprintf("-f %s ", ei.Format))
+		args = append(args, "-f", ei.Format)
 	}
-	ss.WriteString(fmt.Sprintf(`-i %s`, ei.Path))
-	return ss.String()
+	return append(args, "-i", ei.Path)
 }
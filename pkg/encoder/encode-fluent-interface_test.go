@@ -5,11 +5,12 @@ import (
 	"encode-box/pkg/encoder/filtergraph"
 	"fmt"
 	"github.com/stretchr/testify/assert"
+	"path/filepath"
 	"strings"
 	"testing"
 )
 
-func TestEncoderBuilder_getCmd(t *testing.T) {
+func TestEncoderBuilder_getArgs(t *testing.T) {
 	builder := &Builder{}
 	// Build a graph for normalizaing audio
 	fInput := filtergraph.NewInput("0")
@@ -17,20 +18,97 @@ func TestEncoderBuilder_getCmd(t *testing.T) {
 
 	const inputPath = "/tmp/test"
 	const outputPath = "/tmp/testOut"
-	outputOptions := []string{"-c:v libx264rgb", "-b:v 192k "}
-	builder = builder.
+	args, err := builder.
 		AddInput(&FileInput{Path: inputPath}).
-		SetFilterGraph(graph)
+		SetFilterGraph(graph).
+		AddOutputOption("-c:v", "libx264rgb").
+		AddOutputOption("-b:v", "192k").
+		SetOutput(outputPath).
+		getFFmpegArgs()
+	assert.NoError(t, err)
+
+	expectedArgs := []string{"-i", inputPath, "-filter_complex", "[0]dynaudnorm[norm2]", "-c:v", "libx264rgb", "-b:v", "192k", outputPath}
+	assert.Equal(t, expectedArgs, args)
+}
+
+// Stream maps must use the labels allocated when building the graph
+func TestEncoderBuilder_MapStream(t *testing.T) {
+	builder := &Builder{}
+	ctx := context.Background()
+	norm := filtergraph.NewAudioNormalizationFilter(filtergraph.NewInput("1"), filtergraph.Dynaudnorm)
+	enc, err := builder.
+		AddInput(&FileInput{Path: "/tmp/video.mp4"}).
+		AddInput(&FileInput{Path: "/tmp/audio.mp3"}).
+		SetFilterGraph(norm).
+		MapStream(filtergraph.NewInput("0:v")).
+		MapStream(norm).
+		AddOutputOption("-c:v", "copy").
+		SetOutput("/tmp/out.mp4").
+		Build(&ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, `ffmpeg -i /tmp/video.mp4 -i /tmp/audio.mp3 -filter_complex '[1]dynaudnorm[norm2]' -map 0:v -map '[norm2]' -c:v copy /tmp/out.mp4`, enc.GetCommandLine())
+}
+
+// Paths are never split nor altered, whatever they contain
+func TestEncoderBuilder_SpecialPaths(t *testing.T) {
+	builder := &Builder{}
+	args, err := builder.
+		AddInput(&FileInput{Path: `/tmp/my "video".mp4`}).
+		AddInput(&FileInput{Path: "/tmp/it's -filter_complex x.mp3"}).
+		MapStream(filtergraph.NewInput("0:v")).
+		SetOutput("/tmp/out put.mp4").
+		getFFmpegArgs()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"-i", `/tmp/my "video".mp4`, "-i", "/tmp/it's -filter_complex x.mp3", "-map", "0:v", "/tmp/out put.mp4"}, args)
+}
 
-	for _, out := range outputOptions {
-		builder.AddOutputOption(out)
+// Asset keys come from the requests : whatever they contain, each path must stay a single argument,
+// and the logged command line must give back the exact same arguments once parsed by a shell
+func FuzzBuilder_Args(f *testing.F) {
+	for _, key := range []string{"video.mp4", "my video.mp4", `it's "quoted".mp3`, "a;rm -rf b", "$(ls)`ls`", "-filter_complex", "[0]anull[out]", "tab\tnew\nline", ""} {
+		f.Add(key)
 	}
-	cmd := builder.
-		SetOutput(outputPath).
-		getFFmpegCmd()
+	f.Fuzz(func(t *testing.T, key string) {
+		if strings.ContainsRune(key, 0) {
+			// Arguments of a process can't contain NUL bytes
+			t.Skip()
+		}
+		path := filepath.Join("/tmp/assets", key)
+		norm := filtergraph.NewAudioNormalizationFilter(filtergraph.NewInput("1"), filtergraph.Dynaudnorm)
+		builder := &Builder{}
+		args, err := builder.
+			AddInput(&FileInput{Path: path}).
+			AddInput(&FileInput{Path: "/tmp/audio.mp3"}).
+			SetFilterGraph(norm).
+			MapStream(filtergraph.NewInput("0:v")).
+			MapStream(norm).
+			SetOutput(path).
+			getFFmpegArgs()
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"-i", path, "-i", "/tmp/audio.mp3", "-filter_complex", "[1]dynaudnorm[norm2]", "-map", "0:v", "-map", "[norm2]", path}, args)
+
+		ctx := context.Background()
+		assert.Equal(t, append([]string{"ffmpeg"}, args...), splitShellWords(t, NewEncoder(&ctx, args).GetCommandLine()))
+	})
+}
+
+func TestEncoder_GetCommandLine(t *testing.T) {
+	ctx := context.Background()
+	enc := NewEncoder(&ctx, []string{"-i", "/tmp/a b.mp4", "-filter_complex", "[0]volume=2[v];[v]anull[o]", "-metadata", "title=It's", "", "/tmp/out.mp4"})
+	assert.Equal(t, `ffmpeg -i '/tmp/a b.mp4' -filter_complex '[0]volume=2[v];[v]anull[o]' -metadata 'title=It'\''s' '' /tmp/out.mp4`, enc.GetCommandLine())
+}
 
-	expectedCmd := fmt.Sprintf("ffmpeg -i %s -filter_complex \"%s\" %s %s %s", inputPath, strings.TrimSuffix(graph.Build(), ";"), outputOptions[0], outputOptions[1], outputPath)
-	assert.Equal(t, expectedCmd, cmd)
+// An invalid graph is reported before any FFmpeg process is started
+func TestEncoderBuilder_InvalidGraph(t *testing.T) {
+	builder := &Builder{}
+	ctx := context.Background()
+	norm := filtergraph.NewAudioNormalizationFilter(filtergraph.NewInput("1"), filtergraph.Dynaudnorm)
+	_, err := builder.
+		AddInput(&FileInput{Path: "/tmp/audio.mp3"}).
+		SetFilterGraph(norm).
+		SetOutput("/tmp/out.mp4").
+		Build(&ctx)
+	assert.ErrorContains(t, err, "references input 1")
 }
 
 func TestEncoderBuilder_BuildNoInput(t *testing.T) {
@@ -52,7 +130,7 @@ func TestInput_StringWithoutFormat(t *testing.T) {
 	input1 := &FileInput{
 		Path: path,
 	}
-	assert.Equal(t, fmt.Sprintf("-i %s", path), input1.String())
+	assert.Equal(t, []string{"-i", path}, input1.Args())
 }
 
 // Test collapse input when all options are specified
@@ -63,7 +141,7 @@ func TestInput_StringWithFormat(t *testing.T) {
 		Path:   path,
 		Format: format,
 	}
-	assert.Equal(t, fmt.Sprintf("-f %s -i %s", format, path), input1.String())
+	assert.Equal(t, []string{"-f", format, "-i", path}, input1.Args())
 }
 
 // Test collapse input when all options are specified
@@ -73,7 +151,51 @@ func TestInput_StringWithFormatAndOptions(t *testing.T) {
 	input1 := &FileInput{
 		Path:    path,
 		Format:  format,
-		Options: []string{"test"},
+		Options: []string{"-loop", "1"},
+	}
+	assert.Equal(t, []string{"-loop", "1", "-f", format, "-i", path}, input1.Args())
+}
+
+// Return the shell command line of the builder arguments, without the "ffmpeg" binary
+func builderCommandLine(t testing.TB, builder *Builder) string {
+	args, err := builder.getFFmpegArgs()
+	assert.NoError(t, err)
+	return quoteCommandLine(args)
+}
+
+// Split a command line produced by quoteCommandLine the way a POSIX shell would
+func splitShellWords(t testing.TB, line string) []string {
+	var words []string
+	word := strings.Builder{}
+	// A word may be empty (''), it's then only known to exist once a quote is met
+	inWord, quoted := false, false
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case quoted && c == '\'':
+			quoted = false
+		case quoted:
+			word.WriteByte(c)
+		case c == '\'':
+			quoted, inWord = true, true
+		case c == '\\' && i+1 < len(line):
+			i++
+			word.WriteByte(line[i])
+			inWord = true
+		case c == ' ':
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			word.WriteByte(c)
+			inWord = true
+		}
+	}
+	assert.False(t, quoted, "unterminated quote in %q", line)
+	if inWord {
+		words = append(words, word.String())
 	}
-	assert.Equal(t, fmt.Sprintf("test -f %s -i %s", format, path), input1.String())
+	return words
 }
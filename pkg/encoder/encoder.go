@@ -10,8 +10,8 @@ import (
 )
 
 type Encoder struct {
-	// FFMpeg command line to execute
-	cmd string
+	// FFMpeg arguments, without the "ffmpeg" binary itself
+	args []string
 	// Channel to send progress into
 	PChan chan *console_parser.EncodingProgress
 	// Channel to send errors into
@@ -20,13 +20,15 @@ type Encoder struct {
 	Ctx context.Context
 	// Function to execute to Cancel the encoding process
 	Cancel context.CancelFunc
+	// How the video track is produced from the source video. Nil if there is no source video
+	Video *VideoDecision
 }
 
-// NewEncoder Build a new FFMpeg encoder initialized with cmd as a command
-func NewEncoder(ctx *context.Context, cmd string) *Encoder {
+// NewEncoder Build a new FFMpeg encoder running FFmpeg with args
+func NewEncoder(ctx *context.Context, args []string) *Encoder {
 	eCtx, cancel := context.WithCancel(*ctx)
 	return &Encoder{
-		cmd:    cmd,
+		args:   args,
 		PChan:  make(chan *console_parser.EncodingProgress),
 		EChan:  make(chan error),
 		Ctx:    eCtx,
@@ -36,28 +38,7 @@ func NewEncoder(ctx *context.Context, cmd string) *Encoder {
 
 func (e *Encoder) Start() {
 	defer e.Cancel()
-	sanitizedCmd := regexp.MustCompile(`\s+`).ReplaceAllString(e.cmd, " ")
-	// Handle a special case. An FFmpeg command can have a -filter_complex option.
-	// This option can contains spaces, and if it does, the exec.Command won't work.
-	// But we must split all the argument for command exec
-	// So if the command contains a "-filter_complex", we extract it before splitting the command,
-	// and inserting it back later
-	matches := regexp.MustCompile(`-filter_complex "(.+?)"`).FindAllStringSubmatch(sanitizedCmd, 1)
-	hasComplexFilter := len(matches) > 0 && len(matches[0]) == 2
-	var filterGraphString string
-	if hasComplexFilter {
-		filterGraphString = matches[0][1]
-		sanitizedCmd = strings.Replace(sanitizedCmd, filterGraphString, "fg", 1)
-	}
-	arguments := strings.Split(sanitizedCmd, " ")[1:]
-	if hasComplexFilter {
-		for i, arg := range arguments {
-			if strings.HasPrefix(arg, "-filter_complex") {
-				arguments[i+1] = filterGraphString
-			}
-		}
-	}
-	cmd := exec.Command("ffmpeg", arguments...)
+	cmd := exec.Command("ffmpeg", e.args...)
 
 	// FFMpeg pipe output in stderr for some reason
 	stderr, err := cmd.StderrPipe()
@@ -76,8 +57,27 @@ func (e *Encoder) Start() {
 	}
 }
 
+// GetCommandLine Return the FFmpeg command line, quoted to be pasted in a POSIX shell
 func (e *Encoder) GetCommandLine() string {
-	return e.cmd
+	return quoteCommandLine(append([]string{"ffmpeg"}, e.args...))
+}
+
+// Characters an argument can contain without being quoted in a shell
+var shellSafeRegex = regexp.MustCompile(`^[a-zA-Z0-9_@%+=:,./-]+$`)
+
+// Join args into a command line, quoting each argument containing any shell special character
+func quoteCommandLine(args []string) string {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		if shellSafeRegex.MatchString(arg) {
+			quoted[i] = arg
+			continue
+		}
+		// Nothing is special inside single quotes, but a single quote can't be escaped inside them.
+		// It must be put outside, escaped
+		quoted[i] = "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+	}
+	return strings.Join(quoted, " ")
 }
 
 ////ffmpeg -i ./part0.ogg -i part1.ogg  -filter_complex '[0][1]concat=n=2:v=0:a=1[out]' -map [out] output.ogg
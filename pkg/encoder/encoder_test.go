@@ -10,12 +10,14 @@ import (
 	"time"
 )
 
-const (
-	ResDir = "../../resources"
+const ResDir = "../../resources"
+
+// FFmpeg arguments of the test encodings, the output being appended to them
+var (
 	// Handy ffmpeg command with an infinite audio source and video source, this allows for a never-ending encoding
-	InfiniteCommand = "ffmpeg -f lavfi -i color=black:s=1280x720:r=25 -f lavfi -i anullsrc=r=48000:cl=stereo -map 0:v -map 1:a -c:v libx264rgb %s"
-	WithFilters     = "ffmpeg -i ../../resources/test/video.mp4 -i ../../resources/test/audio.m4a -filter_complex \"[1]loudnorm=I=-16:TP=-1.5:LRA=11[norm_spDdr];[norm_spDdr]aformat=sample_fmts=fltp:sample_rates=44100:channel_layouts=stereo[norm_yEuGz];[norm_yEuGz]asplit=2[scm_mixed_eNulz][sco_mixed_eNulz];[0][scm_mixed_eNulz]sidechaincompress=threshold=0.05:ratio=5:level_sc=0.8[mmc_mixed_eNulz];[mmc_mixed_eNulz][sco_mixed_eNulz]amix=weights=0.2 1.0[mixed_eNulz]\" -map 0:v -map [mixed_eNulz] %s"
-	InputNotExists  = "ffmpeg -i ./meh.mp4 -map 0:v -map 1:a -c:v libx264rgb %s"
+	InfiniteArgs       = []string{"-f", "lavfi", "-i", "color=black:s=1280x720:r=25", "-f", "lavfi", "-i", "anullsrc=r=48000:cl=stereo", "-map", "0:v", "-map", "1:a", "-c:v", "libx264rgb"}
+	WithFiltersArgs    = []string{"-i", "../../resources/test/video.mp4", "-i", "../../resources/test/audio.m4a", "-filter_complex", "[1]loudnorm=I=-16:TP=-1.5:LRA=11[norm_spDdr];[norm_spDdr]aformat=sample_fmts=fltp:sample_rates=44100:channel_layouts=stereo[norm_yEuGz];[norm_yEuGz]asplit=2[scm_mixed_eNulz][sco_mixed_eNulz];[0][scm_mixed_eNulz]sidechaincompress=threshold=0.05:ratio=5:level_sc=0.8[mmc_mixed_eNulz];[mmc_mixed_eNulz][sco_mixed_eNulz]amix=weights=0.2 1[mixed_eNulz]", "-map", "0:v", "-map", "[mixed_eNulz]"}
+	InputNotExistsArgs = []string{"-i", "./meh.mp4", "-map", "0:v", "-map", "1:a", "-c:v", "libx264rgb"}
 )
 
 func TestEncoder_StartInfinite(t *testing.T) {
@@ -25,7 +27,7 @@ func TestEncoder_StartInfinite(t *testing.T) {
 	}
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	//testImage := Path.Join(ResDir, "/test/test.jpg")
-	enc := NewEncoder(&ctx, fmt.Sprintf(InfiniteCommand, path.Join(dir, "test.mp4")))
+	enc := NewEncoder(&ctx, append(InfiniteArgs, path.Join(dir, "test.mp4")))
 	defer cancel()
 	go enc.Start()
 	for {
@@ -49,7 +51,7 @@ func TestEncoder_StartWithFilters(t *testing.T) {
 	}
 	//testImage := Path.Join(ResDir, "/test/test.jpg")
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	enc := NewEncoder(&ctx, fmt.Sprintf(WithFilters, path.Join(dir, "test.mp4")))
+	enc := NewEncoder(&ctx, append(WithFiltersArgs, path.Join(dir, "test.mp4")))
 	defer cancel()
 	go enc.Start()
 	for {
@@ -73,7 +75,7 @@ func TestEncoder_StartError(t *testing.T) {
 	}
 	//testImage := Path.Join(ResDir, "/test/test.jpg")
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	enc := NewEncoder(&ctx, fmt.Sprintf(InputNotExists, path.Join(dir, "test.mp4")))
+	enc := NewEncoder(&ctx, append(InputNotExistsArgs, path.Join(dir, "test.mp4")))
 	defer cancel()
 	go enc.Start()
 	errorTriggered := false
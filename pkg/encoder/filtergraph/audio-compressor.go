@@ -0,0 +1,70 @@
+package filtergraph
+
+import (
+	"fmt"
+	"strings"
+)
+
+// AudioCompressorFilter Reduce the dynamic range of a track
+// Documentation : https://ffmpeg.org/ffmpeg-filters.html#acompressor
+type AudioCompressorFilter struct {
+	Node
+	params CompressorParams
+}
+
+// CompressorParams Parameters of the compressor filter. Zero values are replaced by the defaults
+type CompressorParams struct {
+	// Level above which the signal is compressed (dB). Default to -18
+	Threshold float64 `json:"threshold"`
+	// Compression ratio. Default to 3
+	Ratio float64 `json:"ratio"`
+	// Time before the compression kicks in (ms). Default to 20
+	Attack float64 `json:"attack"`
+	// Time before the compression stops (ms). Default to 250
+	Release float64 `json:"release"`
+	// Gain applied after the compression (dB). Default to 0
+	Makeup float64 `json:"makeup"`
+}
+
+var defaultCompressor = CompressorParams{Threshold: -18, Ratio: 3, Attack: 20, Release: 250}
+
+func NewAudioCompressorFilter(target Filter, params CompressorParams) *AudioCompressorFilter {
+	if params.Threshold == 0 {
+		params.Threshold = defaultCompressor.Threshold
+	}
+	if params.Ratio == 0 {
+		params.Ratio = defaultCompressor.Ratio
+	}
+	if params.Attack == 0 {
+		params.Attack = defaultCompressor.Attack
+	}
+	if params.Release == 0 {
+		params.Release = defaultCompressor.Release
+	}
+	return &AudioCompressorFilter{
+		Node{
+			name:     fmt.Sprintf("comp_%s", uniqueSuffix()),
+			children: []Filter{target},
+		},
+		params}
+}
+
+func (acf *AudioCompressorFilter) Build() string {
+	// Expected format : [0]acompressor=threshold=-18dB:ratio=3:attack=20:release=250:makeup=0dB[r1]
+	ss := strings.Builder{}
+	// First let the children Build themselves
+	ss.WriteString(acf.buildChildren())
+	ss.WriteString(fmt.Sprintf("[%s]acompressor=threshold=%sdB:ratio=%s:attack=%s:release=%s:makeup=%sdB[%s];",
+		acf.children[0].Id(),
+		formatFloat(acf.params.Threshold),
+		formatFloat(acf.params.Ratio),
+		formatFloat(acf.params.Attack),
+		formatFloat(acf.params.Release),
+		formatFloat(acf.params.Makeup),
+		acf.Id()))
+	return ss.String()
+}
+
+func (acf *AudioCompressorFilter) Id() string {
+	return acf.label()
+}
@@ -5,34 +5,45 @@ import (
 	"strings"
 )
 
-// AudioConcatFilter Put one or more audio one after another
-// /!\ The audio must use the same codec /!\
+// AudioConcatFilter Put one or more audio one after another.
+// Each input is first converted to the same format, as concat requires all its inputs to share
+// their sample rate, sample format and channel layout. Audio parts of any codec and format can thus be concatenated
 // Documentation : https://ffmpeg.org/ffmpeg-filters.html#concat
 type AudioConcatFilter struct {
 	Node
+	// Format all inputs are converted into
+	format AudioFormat
 }
 
-func NewAudioConcatFilter(inputs ...Filter) *AudioConcatFilter {
+func NewAudioConcatFilter(format AudioFormat, inputs ...Filter) *AudioConcatFilter {
 	return &AudioConcatFilter{Node{
-		name:     fmt.Sprintf("concat_%s", randString(5)),
+		name:     fmt.Sprintf("concat_%s", uniqueSuffix()),
 		children: inputs,
-	}}
+	}, format.WithDefaults()}
 }
 
 func (cfn *AudioConcatFilter) Build() string {
-	// Expected format : children_build;[children_id_1][children_id_2]concat=n=2:v=0:a=1[input_id]
+	// Expected format : children_build;[children_id_1]aresample=44100,aformat=...[in_1];[children_id_2]aresample=44100,aformat=...[in_2];[in_1][in_2]concat=n=2:v=0:a=1[input_id]
 	ss := strings.Builder{}
 	// First let the children Build themselves
-	for _, c := range cfn.children {
-		ss.WriteString(c.Build())
+	ss.WriteString(cfn.buildChildren())
+	// Then normalize each of them. aformat up/down-mixes the channels to the layout if required
+	for i, c := range cfn.children {
+		ss.WriteString(fmt.Sprintf("[%s]aresample=%s,aformat=sample_fmts=%s:channel_layouts=%s[%s];",
+			c.Id(), cfn.format.SampleRate, cfn.format.SampleFormat, cfn.format.ChannelLayout, cfn.inputName(i)))
 	}
-	for _, c := range cfn.children {
-		ss.WriteString(fmt.Sprintf("[%s]", c.Id()))
+	for i := range cfn.children {
+		ss.WriteString(fmt.Sprintf("[%s]", cfn.inputName(i)))
 	}
 	ss.WriteString(fmt.Sprintf("concat=n=%d:v=0:a=1[%s];", len(cfn.children), cfn.Id()))
 	return ss.String()
 }
 
 func (cfn *AudioConcatFilter) Id() string {
-	return cfn.name
+	return cfn.label()
+}
+
+// Id of the i-th input, once normalized
+func (cfn *AudioConcatFilter) inputName(i int) string {
+	return fmt.Sprintf("%s_in%d", cfn.Id(), i)
 }
@@ -0,0 +1,76 @@
+package filtergraph
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// CutFilter Remove some parts of the target audio or video, closing the gaps
+// Documentation : https://ffmpeg.org/ffmpeg-filters.html#select_002c-aselect
+type CutFilter struct {
+	Node
+	// Parts of the stream to remove
+	cuts []TimeRange
+	// Whether the target is a video or an audio stream
+	video bool
+}
+
+// TimeRange A part of a stream, between two timestamps
+type TimeRange struct {
+	Start time.Duration `json:"start"`
+	End   time.Duration `json:"end"`
+}
+
+// Duration Length of the range
+func (tr TimeRange) Duration() time.Duration {
+	return tr.End - tr.Start
+}
+
+// NewAudioCutFilter Remove the cuts from the target audio
+func NewAudioCutFilter(target Filter, cuts []TimeRange) *CutFilter {
+	return &CutFilter{
+		Node{
+			name:     fmt.Sprintf("cut_%s", uniqueSuffix()),
+			children: []Filter{target},
+		},
+		cuts,
+		false}
+}
+
+// NewVideoCutFilter Remove the cuts from the target video. Removing the same cuts from an audio
+// and a video keeps them in sync
+func NewVideoCutFilter(target Filter, cuts []TimeRange) *CutFilter {
+	f := NewAudioCutFilter(target, cuts)
+	f.video = true
+	return f
+}
+
+func (cf *CutFilter) Build() string {
+	// Expected format : [0]aselect='not(between(t,0,2.5)+between(t,10,12))',asetpts=N/SR/TB[r1]
+	// or, for a video : [0:v]select='not(between(t,0,2.5)+between(t,10,12))',setpts=N/FRAME_RATE/TB[r1]
+	ss := strings.Builder{}
+	// First let the children Build themselves
+	ss.WriteString(cf.buildChildren())
+	ranges := make([]string, 0, len(cf.cuts))
+	for _, c := range cf.cuts {
+		ranges = append(ranges, fmt.Sprintf("between(t,%s,%s)", formatFloat(c.Start.Seconds()), formatFloat(c.End.Seconds())))
+	}
+	// Once samples or frames are dropped, timestamps must be recomputed to close the gaps
+	prefix, pts := "a", "N/SR/TB"
+	if cf.video {
+		prefix, pts = "", "N/FRAME_RATE/TB"
+	}
+	ss.WriteString(fmt.Sprintf("[%s]%sselect='not(%s)',%ssetpts=%s[%s];",
+		cf.children[0].Id(),
+		prefix,
+		strings.Join(ranges, "+"),
+		prefix,
+		pts,
+		cf.Id()))
+	return ss.String()
+}
+
+func (cf *CutFilter) Id() string {
+	return cf.label()
+}
@@ -0,0 +1,55 @@
+package filtergraph
+
+import (
+	"fmt"
+	"strings"
+)
+
+// AudioDenoiseFilter Reduce the background noise of a track using FFT
+// Documentation : https://ffmpeg.org/ffmpeg-filters.html#afftdn
+type AudioDenoiseFilter struct {
+	Node
+	params DenoiseParams
+}
+
+// DenoiseParams Parameters of the denoise filter. Zero values are replaced by the defaults
+type DenoiseParams struct {
+	// Amount of noise reduction (dB). Default to 12
+	Reduction float64 `json:"reduction"`
+	// Noise floor (dB). Default to -50
+	NoiseFloor float64 `json:"noiseFloor"`
+}
+
+var defaultDenoise = DenoiseParams{Reduction: 12, NoiseFloor: -50}
+
+func NewAudioDenoiseFilter(target Filter, params DenoiseParams) *AudioDenoiseFilter {
+	if params.Reduction == 0 {
+		params.Reduction = defaultDenoise.Reduction
+	}
+	if params.NoiseFloor == 0 {
+		params.NoiseFloor = defaultDenoise.NoiseFloor
+	}
+	return &AudioDenoiseFilter{
+		Node{
+			name:     fmt.Sprintf("denoise_%s", uniqueSuffix()),
+			children: []Filter{target},
+		},
+		params}
+}
+
+func (adf *AudioDenoiseFilter) Build() string {
+	// Expected format : [0]afftdn=nr=12:nf=-50[r1]
+	ss := strings.Builder{}
+	// First let the children Build themselves
+	ss.WriteString(adf.buildChildren())
+	ss.WriteString(fmt.Sprintf("[%s]afftdn=nr=%s:nf=%s[%s];",
+		adf.children[0].Id(),
+		formatFloat(adf.params.Reduction),
+		formatFloat(adf.params.NoiseFloor),
+		adf.Id()))
+	return ss.String()
+}
+
+func (adf *AudioDenoiseFilter) Id() string {
+	return adf.label()
+}
@@ -0,0 +1,68 @@
+package filtergraph
+
+import (
+	"fmt"
+	"strings"
+)
+
+// AudioGateFilter Attenuate a track when its level is under a threshold, muting
+// a speaker while they're not talking
+// Documentation : https://ffmpeg.org/ffmpeg-filters.html#agate
+type AudioGateFilter struct {
+	Node
+	params GateParams
+}
+
+// GateParams Parameters of the gate filter. Zero values are replaced by the defaults
+type GateParams struct {
+	// Level under which the signal is attenuated (dB). Default to -40
+	Threshold float64 `json:"threshold"`
+	// Attenuation ratio. Default to 2
+	Ratio float64 `json:"ratio"`
+	// Time before the gate opens (ms). Default to 10
+	Attack float64 `json:"attack"`
+	// Time before the gate closes (ms). Default to 250
+	Release float64 `json:"release"`
+}
+
+var defaultGate = GateParams{Threshold: -40, Ratio: 2, Attack: 10, Release: 250}
+
+func NewAudioGateFilter(target Filter, params GateParams) *AudioGateFilter {
+	if params.Threshold == 0 {
+		params.Threshold = defaultGate.Threshold
+	}
+	if params.Ratio == 0 {
+		params.Ratio = defaultGate.Ratio
+	}
+	if params.Attack == 0 {
+		params.Attack = defaultGate.Attack
+	}
+	if params.Release == 0 {
+		params.Release = defaultGate.Release
+	}
+	return &AudioGateFilter{
+		Node{
+			name:     fmt.Sprintf("gate_%s", uniqueSuffix()),
+			children: []Filter{target},
+		},
+		params}
+}
+
+func (agf *AudioGateFilter) Build() string {
+	// Expected format : [0]agate=threshold=-40dB:ratio=2:attack=10:release=250[r1]
+	ss := strings.Builder{}
+	// First let the children Build themselves
+	ss.WriteString(agf.buildChildren())
+	ss.WriteString(fmt.Sprintf("[%s]agate=threshold=%sdB:ratio=%s:attack=%s:release=%s[%s];",
+		agf.children[0].Id(),
+		formatFloat(agf.params.Threshold),
+		formatFloat(agf.params.Ratio),
+		formatFloat(agf.params.Attack),
+		formatFloat(agf.params.Release),
+		agf.Id()))
+	return ss.String()
+}
+
+func (agf *AudioGateFilter) Id() string {
+	return agf.label()
+}
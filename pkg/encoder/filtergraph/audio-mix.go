@@ -2,16 +2,18 @@ package filtergraph
 
 import (
 	"fmt"
+	"strconv"
 	"strings"
 )
 
-// AudioMix mixing two audio tracks, with a modulated volume
+// AudioMix mixing two audio tracks, with a modulated volume, or any number of
+// audio tracks together
 // Documentation : https://ffmpeg.org/ffmpeg-filters.html#amix
 type AudioMix struct {
 	Node
 	mode AudioMixMode
-	// Relative volume of [main, side] channels
-	weights [2]float32
+	// Relative volume of each channel. For two channels, [main, side]
+	weights []float32
 	// Main channel index to find in both weights and children
 	mainChannelIndex uint8
 	// Side channel index to find in both weights and children
@@ -28,10 +30,25 @@ const (
 func NewAudioMixFilter(main Filter, side Filter, mode AudioMixMode, weights [2]float32) *AudioMix {
 	return &AudioMix{
 		Node: Node{
-			name:     fmt.Sprintf("mixed_%s", randString(5)),
+			name:     fmt.Sprintf("mixed_%s", uniqueSuffix()),
 			children: []Filter{main, side},
 		},
 		mode:             mode,
+		weights:          weights[:],
+		mainChannelIndex: 0,
+		sideChannelIndex: 1,
+	}
+}
+
+// NewAudioMultiMixFilter Mix all inputs together, playing simultaneously.
+// Weights are the relative volume of each input, and must be as many as the inputs
+func NewAudioMultiMixFilter(inputs []Filter, weights []float32) *AudioMix {
+	return &AudioMix{
+		Node: Node{
+			name:     fmt.Sprintf("mixed_%s", uniqueSuffix()),
+			children: inputs,
+		},
+		mode:             WithoutModulation,
 		weights:          weights,
 		mainChannelIndex: 0,
 		sideChannelIndex: 1,
@@ -52,20 +69,18 @@ func (amf *AudioMix) Build() string {
 
 func (amf *AudioMix) withoutModulation() string {
 	// Expected format : [main][side]amix=weights=1 0.2[res]
+	// or, with more than two inputs : [0][1][2]amix=inputs=3:weights=1 1 1[res]
 	ss := strings.Builder{}
+	ss.WriteString(amf.buildChildren())
 	for _, c := range amf.children {
-		ss.WriteString(c.Build())
+		ss.WriteString(fmt.Sprintf("[%s]", c.Id()))
 	}
-
-	ss.WriteString(
-		fmt.Sprintf("[%s][%s]amix=weights=%.1f %.1f[%s];",
-			amf.children[amf.mainChannelIndex].Id(),
-			amf.children[amf.sideChannelIndex].Id(),
-			amf.weights[amf.mainChannelIndex],
-			amf.weights[amf.sideChannelIndex],
-			amf.Id(),
-		),
-	)
+	ss.WriteString("amix=")
+	// amix defaults to two inputs
+	if len(amf.children) != 2 {
+		ss.WriteString(fmt.Sprintf("inputs=%d:", len(amf.children)))
+	}
+	ss.WriteString(fmt.Sprintf("weights=%s[%s];", formatWeights(amf.weights...), amf.Id()))
 	return ss.String()
 }
 
@@ -74,9 +89,7 @@ func (amf *AudioMix) withModulation() string {
 	// nolint:lll  "[1]asplit=2[sc][v1];[r1][sc]sidechaincompress=threshold=0.05:ratio=5:level_sc=0.8[bg];[bg][v1]amix=weights=0.2 1[a3]"
 	ss := strings.Builder{}
 	// First let the children Build themselves
-	for _, c := range amf.children {
-		ss.WriteString(c.Build())
-	}
+	ss.WriteString(amf.buildChildren())
 	var (
 		SideChannelModulate  = fmt.Sprintf("scm_%s", amf.Id())
 		SideChannelOriginal  = fmt.Sprintf("sco_%s", amf.Id())
@@ -103,16 +116,25 @@ func (amf *AudioMix) withModulation() string {
 	// Finally, mix the modulated main channel with the original main channel
 	// 0.2 1
 	ss.WriteString(
-		fmt.Sprintf("[%s][%s]amix=weights=%.1f %.1f[%s];",
+		fmt.Sprintf("[%s][%s]amix=weights=%s[%s];",
 			ModulatedMainChannel,
 			SideChannelOriginal,
-			amf.weights[amf.mainChannelIndex],
-			amf.weights[amf.sideChannelIndex],
+			formatWeights(amf.weights[amf.mainChannelIndex], amf.weights[amf.sideChannelIndex]),
 			amf.Id()))
 
 	return ss.String()
 }
 
 func (amf *AudioMix) Id() string {
-	return amf.name
+	return amf.label()
+}
+
+// Format the weights as amix expects them, in their shortest representation and separated by spaces.
+// Rounding them would change the mix, or even mute a track
+func formatWeights(weights ...float32) string {
+	formatted := make([]string, 0, len(weights))
+	for _, w := range weights {
+		formatted = append(formatted, strconv.FormatFloat(float64(w), 'f', -1, 32))
+	}
+	return strings.Join(formatted, " ")
 }
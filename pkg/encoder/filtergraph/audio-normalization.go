@@ -2,6 +2,7 @@ package filtergraph
 
 import (
 	"fmt"
+	"strconv"
 	"strings"
 )
 
@@ -10,6 +11,13 @@ type AudioNormalizationFilter struct {
 	Node
 	// Mode of normalization
 	mode AudioNormalizationMode
+	// Loudness targets, only used with the Loudnorm mode
+	targets LoudnormTargets
+	// Values measured by a previous analysis pass. If defined, loudnorm will use them
+	// to perform a linear normalization (second pass)
+	measured *LoudnormMeasurement
+	// If true, loudnorm will only print its measurements as JSON (first pass)
+	analysis bool
 }
 
 type AudioNormalizationMode uint8
@@ -26,25 +34,77 @@ const (
 	Speechnorm
 )
 
+// LoudnormTargets EBU R128 targets of the loudnorm filter
+type LoudnormTargets struct {
+	// Integrated loudness (LUFS)
+	I float64
+	// Maximum true peak (dBTP)
+	TP float64
+	// Loudness range (LU)
+	LRA float64
+}
+
+// PodcastLoudness Usual loudness for podcasts and streaming platforms
+func PodcastLoudness() LoudnormTargets {
+	return LoudnormTargets{I: -16, TP: -1.5, LRA: 11}
+}
+
+// BroadcastLoudness Loudness as defined by the EBU R128 recommendation for broadcasting
+func BroadcastLoudness() LoudnormTargets {
+	return LoudnormTargets{I: -23, TP: -1, LRA: 7}
+}
+
+// LoudnormMeasurement Input stats measured by a first loudnorm pass
+type LoudnormMeasurement struct {
+	// Measured integrated loudness
+	I float64
+	// Measured true peak
+	TP float64
+	// Measured loudness range
+	LRA float64
+	// Measured threshold
+	Thresh float64
+	// Offset gain to apply
+	Offset float64
+}
+
 func NewAudioNormalizationFilter(target Filter, mode AudioNormalizationMode) *AudioNormalizationFilter {
 	return &AudioNormalizationFilter{
-		Node{
-			name:     fmt.Sprintf("norm_%s", randString(5)),
+		Node: Node{
+			name:     fmt.Sprintf("norm_%s", uniqueSuffix()),
 			children: []Filter{target},
 		},
-		mode}
+		mode:    mode,
+		targets: PodcastLoudness(),
+	}
+}
+
+// NewLoudnormFilter Normalize the target with loudnorm using custom targets.
+// If measured is nil, a single-pass (dynamic) normalization is performed. Otherwise, the measured
+// values are used to perform a linear normalization
+func NewLoudnormFilter(target Filter, targets LoudnormTargets, measured *LoudnormMeasurement) *AudioNormalizationFilter {
+	f := NewAudioNormalizationFilter(target, Loudnorm)
+	f.targets = targets
+	f.measured = measured
+	return f
+}
+
+// NewLoudnormAnalysisFilter First pass of a two-pass loudnorm normalization.
+// The filter only prints the measured values of the target as JSON in the FFmpeg output
+func NewLoudnormAnalysisFilter(target Filter, targets LoudnormTargets) *AudioNormalizationFilter {
+	f := NewLoudnormFilter(target, targets, nil)
+	f.analysis = true
+	return f
 }
 
 func (amf *AudioNormalizationFilter) Build() string {
 	// Expected format : [0:a]loudnorm=I=-16:TP=-1.5:LRA=11[norm]
 	ss := strings.Builder{}
 	// First let the children Build themselves
-	for _, c := range amf.children {
-		ss.WriteString(c.Build())
-	}
+	ss.WriteString(amf.buildChildren())
 	switch amf.mode {
 	case Loudnorm:
-		ss.WriteString(fmt.Sprintf("[%s]loudnorm=I=-16:TP=-1.5:LRA=11[%s];", amf.children[0].Id(), amf.Id()))
+		ss.WriteString(fmt.Sprintf("[%s]loudnorm=%s[%s];", amf.children[0].Id(), amf.loudnormArgs(), amf.Id()))
 	case Dynaudnorm:
 		ss.WriteString(fmt.Sprintf("[%s]dynaudnorm[%s];", amf.children[0].Id(), amf.Id()))
 	case Speechnorm:
@@ -53,6 +113,31 @@ func (amf *AudioNormalizationFilter) Build() string {
 	return ss.String()
 }
 
+// Arguments of the loudnorm filter, depending on the pass
+func (amf *AudioNormalizationFilter) loudnormArgs() string {
+	args := fmt.Sprintf("I=%s:TP=%s:LRA=%s",
+		formatFloat(amf.targets.I),
+		formatFloat(amf.targets.TP),
+		formatFloat(amf.targets.LRA))
+	if amf.analysis {
+		return args + ":print_format=json"
+	}
+	if amf.measured != nil {
+		args += fmt.Sprintf(":measured_I=%s:measured_TP=%s:measured_LRA=%s:measured_thresh=%s:offset=%s:linear=true",
+			formatFloat(amf.measured.I),
+			formatFloat(amf.measured.TP),
+			formatFloat(amf.measured.LRA),
+			formatFloat(amf.measured.Thresh),
+			formatFloat(amf.measured.Offset))
+	}
+	return args
+}
+
 func (amf *AudioNormalizationFilter) Id() string {
-	return amf.name
+	return amf.label()
+}
+
+// Format a float in its shortest representation (-16 instead of -16.000000)
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
 }
@@ -0,0 +1,52 @@
+package filtergraph
+
+import (
+	"fmt"
+	"strings"
+)
+
+// AudioPassFilter Cut frequencies below (highpass) or above (lowpass) a given frequency
+// Documentation : https://ffmpeg.org/ffmpeg-filters.html#highpass
+// Documentation : https://ffmpeg.org/ffmpeg-filters.html#lowpass
+type AudioPassFilter struct {
+	Node
+	mode AudioPassMode
+	// Cutoff frequency (Hz)
+	frequency int
+}
+
+type AudioPassMode uint8
+
+const (
+	// Remove frequencies below the cutoff, useful to remove rumble and plosives
+	Highpass AudioPassMode = iota
+	// Remove frequencies above the cutoff, useful to remove hiss
+	Lowpass
+)
+
+func NewAudioPassFilter(target Filter, mode AudioPassMode, frequency int) *AudioPassFilter {
+	return &AudioPassFilter{
+		Node{
+			name:     fmt.Sprintf("pass_%s", uniqueSuffix()),
+			children: []Filter{target},
+		},
+		mode,
+		frequency}
+}
+
+func (apf *AudioPassFilter) Build() string {
+	// Expected format : [0]highpass=f=100[r1]
+	ss := strings.Builder{}
+	// First let the children Build themselves
+	ss.WriteString(apf.buildChildren())
+	filterName := "highpass"
+	if apf.mode == Lowpass {
+		filterName = "lowpass"
+	}
+	ss.WriteString(fmt.Sprintf("[%s]%s=f=%d[%s];", apf.children[0].Id(), filterName, apf.frequency, apf.Id()))
+	return ss.String()
+}
+
+func (apf *AudioPassFilter) Id() string {
+	return apf.label()
+}
@@ -5,43 +5,106 @@ import (
 	"strings"
 )
 
+// AudioResampleFilter Convert the audio to the sample rate, sample format and channel layout of a format.
+// Channels are up/down-mixed if the layout differs
+// Documentation : https://ffmpeg.org/ffmpeg-filters.html#aformat-1
 type AudioResampleFilter struct {
 	Node
-	// Sampling rate to resample the audio into
-	targetFormat Sampling
+	// Format to convert the audio into
+	targetFormat AudioFormat
 }
 
-func NewAudioResampleFilter(target Filter, targetFormat Sampling) *AudioResampleFilter {
+func NewAudioResampleFilter(target Filter, targetFormat AudioFormat) *AudioResampleFilter {
 	return &AudioResampleFilter{Node{
-		name:     fmt.Sprintf("norm_%s", randString(5)),
+		name:     fmt.Sprintf("norm_%s", uniqueSuffix()),
 		children: []Filter{target},
-	}, targetFormat}
+	}, targetFormat.WithDefaults()}
 }
 
 func (arf *AudioResampleFilter) Build() string {
 	// Expected format : [0]aformat=sample_fmts=fltp:sample_rates=44100:channel_layouts=stereo[r1]
 	ss := strings.Builder{}
 	// First let the children Build themselves
-	for _, c := range arf.children {
-		ss.WriteString(c.Build())
-	}
+	ss.WriteString(arf.buildChildren())
 	ss.WriteString(
-		fmt.Sprintf("[%s]aformat=sample_fmts=fltp:sample_rates=%s:channel_layouts=stereo[%s];",
+		fmt.Sprintf("[%s]aformat=sample_fmts=%s:sample_rates=%s:channel_layouts=%s[%s];",
 			arf.children[0].Id(),
-			arf.targetFormat,
+			arf.targetFormat.SampleFormat,
+			arf.targetFormat.SampleRate,
+			arf.targetFormat.ChannelLayout,
 			arf.Id()))
 
 	return ss.String()
 }
 
 func (arf *AudioResampleFilter) Id() string {
-	return arf.name
+	return arf.label()
 }
 
 // Sampling rates
 type Sampling string
 
 const (
+	K22 Sampling = "22050"
+	K32 Sampling = "32000"
 	K44 Sampling = "44100"
-	K48          = "48000"
+	K48 Sampling = "48000"
+	K96 Sampling = "96000"
+)
+
+// SampleFormat Representation of each sample. Planar formats end with "p"
+type SampleFormat string
+
+const (
+	// 32 bits float, planar. This is what most lossy encoders (aac, opus...) use internally
+	Fltp SampleFormat = "fltp"
+	// 16 bits integer, interleaved. The most common one for uncompressed audio
+	S16 SampleFormat = "s16"
+	// 32 bits integer, interleaved
+	S32 SampleFormat = "s32"
 )
+
+// ChannelLayout Number and disposition of the audio channels
+type ChannelLayout string
+
+const (
+	Mono       ChannelLayout = "mono"
+	Stereo     ChannelLayout = "stereo"
+	Surround51 ChannelLayout = "5.1"
+)
+
+// Channels Number of channels of the layout
+func (cl ChannelLayout) Channels() int {
+	switch cl {
+	case Mono:
+		return 1
+	case Surround51:
+		return 6
+	default:
+		return 2
+	}
+}
+
+// AudioFormat Sample rate, sample format and channel layout of an audio stream
+type AudioFormat struct {
+	SampleRate    Sampling
+	SampleFormat  SampleFormat
+	ChannelLayout ChannelLayout
+}
+
+// DefaultAudioFormat 44.1kHz stereo
+var DefaultAudioFormat = AudioFormat{SampleRate: K44, SampleFormat: Fltp, ChannelLayout: Stereo}
+
+// WithDefaults Return the format with DefaultAudioFormat values for all unset fields
+func (af AudioFormat) WithDefaults() AudioFormat {
+	if af.SampleRate == "" {
+		af.SampleRate = DefaultAudioFormat.SampleRate
+	}
+	if af.SampleFormat == "" {
+		af.SampleFormat = DefaultAudioFormat.SampleFormat
+	}
+	if af.ChannelLayout == "" {
+		af.ChannelLayout = DefaultAudioFormat.ChannelLayout
+	}
+	return af
+}
@@ -0,0 +1,45 @@
+package filtergraph
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// SilenceDetectFilter Analysis filter, logging every silence of the target in the FFmpeg output.
+// The audio itself is left untouched
+// Documentation : https://ffmpeg.org/ffmpeg-filters.html#silencedetect
+type SilenceDetectFilter struct {
+	Node
+	// Level under which the audio is considered silent (dB)
+	threshold float64
+	// Minimum duration of a silence
+	minDuration time.Duration
+}
+
+func NewSilenceDetectFilter(target Filter, threshold float64, minDuration time.Duration) *SilenceDetectFilter {
+	return &SilenceDetectFilter{
+		Node{
+			name:     fmt.Sprintf("silence_%s", uniqueSuffix()),
+			children: []Filter{target},
+		},
+		threshold,
+		minDuration}
+}
+
+func (sdf *SilenceDetectFilter) Build() string {
+	// Expected format : [0]silencedetect=n=-50dB:d=2[r1]
+	ss := strings.Builder{}
+	// First let the children Build themselves
+	ss.WriteString(sdf.buildChildren())
+	ss.WriteString(fmt.Sprintf("[%s]silencedetect=n=%sdB:d=%s[%s];",
+		sdf.children[0].Id(),
+		formatFloat(sdf.threshold),
+		formatFloat(sdf.minDuration.Seconds()),
+		sdf.Id()))
+	return ss.String()
+}
+
+func (sdf *SilenceDetectFilter) Id() string {
+	return sdf.label()
+}
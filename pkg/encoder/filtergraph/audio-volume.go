@@ -9,29 +9,40 @@ type AudioVolumeFilter struct {
 	Node
 	// From 0 to 1, the volume to apply to the audio
 	targetVolume float32
+	// If defined, a gain in dB is applied instead of targetVolume
+	gain *float64
 }
 
 func NewAudioVolumeFilter(target Filter, targetVolume float32) *AudioVolumeFilter {
 	return &AudioVolumeFilter{
-		Node{
-			name:     fmt.Sprintf("vol_%s", randString(5)),
+		Node: Node{
+			name:     fmt.Sprintf("vol_%s", uniqueSuffix()),
 			children: []Filter{target},
 		},
-		targetVolume}
+		targetVolume: targetVolume}
+}
+
+// NewAudioGainFilter Amplify (or attenuate, with a negative value) the target by gain dB
+func NewAudioGainFilter(target Filter, gain float64) *AudioVolumeFilter {
+	f := NewAudioVolumeFilter(target, 1)
+	f.gain = &gain
+	return f
 }
 
 func (avf *AudioVolumeFilter) Build() string {
 	// Expected format : [0]volume=0.5[r1]
 	ss := strings.Builder{}
 	// First let the children Build themselves
-	for _, c := range avf.children {
-		ss.WriteString(c.Build())
+	ss.WriteString(avf.buildChildren())
+	if avf.gain != nil {
+		ss.WriteString(fmt.Sprintf("[%s]volume=%sdB[%s];", avf.children[0].Id(), formatFloat(*avf.gain), avf.Id()))
+	} else {
+		ss.WriteString(fmt.Sprintf("[%s]volume=%.2f[%s];", avf.children[0].Id(), avf.targetVolume, avf.Id()))
 	}
-	ss.WriteString(fmt.Sprintf("[%s]volume=%.2f[%s];", avf.children[0].Id(), avf.targetVolume, avf.Id()))
 
 	return ss.String()
 }
 
 func (avf *AudioVolumeFilter) Id() string {
-	return avf.name
+	return avf.label()
 }
@@ -0,0 +1,88 @@
+package filtergraph
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ConcatFilter Put one or more segments, each with a video and an audio stream, one after another.
+// This filter has two outputs : Id() is the resulting video, and Audio() the resulting audio
+// /!\ All segments must have the same resolution, framerate and audio format /!\
+// Documentation : https://ffmpeg.org/ffmpeg-filters.html#concat
+type ConcatFilter struct {
+	Node
+}
+
+// Segment A video and its audio, to be concatenated
+type Segment struct {
+	Video Filter
+	Audio Filter
+}
+
+func NewConcatFilter(segments ...Segment) *ConcatFilter {
+	var children []Filter
+	for _, s := range segments {
+		children = append(children, s.Video, s.Audio)
+	}
+	return &ConcatFilter{
+		Node: Node{
+			name:     fmt.Sprintf("vconcat_%s", uniqueSuffix()),
+			children: children,
+		},
+	}
+}
+
+func (cf *ConcatFilter) Build() string {
+	// Expected format : children_build;[v1][a1][v2][a2]concat=n=2:v=1:a=1[video_id][audio_id]
+	ss := strings.Builder{}
+	// First let the children Build themselves
+	ss.WriteString(cf.buildChildren())
+	for _, c := range cf.children {
+		ss.WriteString(fmt.Sprintf("[%s]", c.Id()))
+	}
+	ss.WriteString(fmt.Sprintf("concat=n=%d:v=1:a=1[%s][%s];", len(cf.children)/2, cf.Id(), cf.Audio().Id()))
+	return ss.String()
+}
+
+func (cf *ConcatFilter) Id() string {
+	return cf.label()
+}
+
+// Audio The audio output of the filter
+func (cf *ConcatFilter) Audio() Filter {
+	return newOutputFilter(cf, "a")
+}
+
+func (cf *ConcatFilter) secondaryOutputs() []string {
+	return []string{"a"}
+}
+
+// VideoConcatFilter Put one or more videos one after another
+// /!\ All videos must have the same resolution, framerate and pixel format /!\
+// Documentation : https://ffmpeg.org/ffmpeg-filters.html#concat
+type VideoConcatFilter struct {
+	Node
+}
+
+func NewVideoConcatFilter(inputs ...Filter) *VideoConcatFilter {
+	return &VideoConcatFilter{Node{
+		name:     fmt.Sprintf("vconcat_%s", uniqueSuffix()),
+		children: inputs,
+	}}
+}
+
+func (vcf *VideoConcatFilter) Build() string {
+	// Expected format : children_build;[children_id_1][children_id_2]concat=n=2:v=1:a=0[input_id]
+	ss := strings.Builder{}
+	// First let the children Build themselves
+	ss.WriteString(vcf.buildChildren())
+	for _, c := range vcf.children {
+		ss.WriteString(fmt.Sprintf("[%s]", c.Id()))
+	}
+	ss.WriteString(fmt.Sprintf("concat=n=%d:v=1:a=0[%s];", len(vcf.children), vcf.Id()))
+	return ss.String()
+}
+
+func (vcf *VideoConcatFilter) Id() string {
+	return vcf.label()
+}
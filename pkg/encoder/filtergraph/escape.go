@@ -0,0 +1,23 @@
+package filtergraph
+
+import "strings"
+
+// EscapeValue Escape an arbitrary option value (a path, a text...) to be used in a filtergraph.
+// Two levels of escaping are required : the value is first quoted to be a valid filter option value,
+// and the result is then escaped to be a valid part of the filtergraph description
+// Documentation : https://ffmpeg.org/ffmpeg-filters.html#Notes-on-filtergraph-escaping
+func EscapeValue(value string) string {
+	// First level, quoting. A quote can't be escaped inside quotes, so it must be closed first
+	quoted := "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+	// Second level, filtergraph special characters
+	return graphEscaper.Replace(quoted)
+}
+
+var graphEscaper = strings.NewReplacer(
+	`\`, `\\`,
+	`'`, `\'`,
+	"[", `\[`,
+	"]", `\]`,
+	",", `\,`,
+	";", `\;`,
+)
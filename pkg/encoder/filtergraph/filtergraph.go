@@ -3,7 +3,9 @@
 package filtergraph
 
 import (
-	"math/rand"
+	"strconv"
+	"strings"
+	"sync/atomic"
 )
 
 // Node A single node of an FFMPEG filter Tree
@@ -12,29 +14,51 @@ type Node struct {
 	name string
 	// All filter to be applied before this one can be compiled
 	children []Filter
+	// Graph the node is being emitted in. Nil when the node is built on its own
+	graph *Graph
 }
 type Filter interface {
 	// Build Resolve the graph into a string usable in FFMPEG -filter_complex option
 	Build() string
 	// Return a unique id for this filter
 	Id() string
+	// Return the underlying node
+	node() *Node
 }
 
-// Random string generation
-const letterBytes = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ"
-const (
-	letterIdxBits = 6                    // 6 bits to represent a letter index
-	letterIdxMask = 1<<letterIdxBits - 1 // All 1-bits, as many as letterIdxBits
-)
+func (n *Node) node() *Node {
+	return n
+}
+
+// Label of the node in the Graph it is being emitted in, or its own name when built on its own
+func (n *Node) label() string {
+	if label, labelled := n.graph.labelOf(n); labelled {
+		return label
+	}
+	return n.name
+}
 
-// Return a random string containing n characters
-func randString(n int) string {
-	b := make([]byte, n)
-	for i := 0; i < n; {
-		if idx := int(rand.Int63() & letterIdxMask); idx < len(letterBytes) {
-			b[i] = letterBytes[idx]
-			i++
+// Build all children of the node. When the node is part of a Graph, children already
+// emitted by another node are skipped, so that a node shared by multiple parents is emitted once
+func (n *Node) buildChildren() string {
+	ss := strings.Builder{}
+	for _, c := range n.children {
+		if n.graph != nil {
+			if n.graph.emitted[c.node()] {
+				continue
+			}
+			n.graph.emitted[c.node()] = true
 		}
+		ss.WriteString(c.Build())
 	}
-	return string(b)
+	return ss.String()
+}
+
+// Number of node names allocated so far
+var nameCounter atomic.Uint64
+
+// Return a suffix making a node name unique in the process.
+// Names are only placeholders : a Graph labels all its nodes deterministically
+func uniqueSuffix() string {
+	return strconv.FormatUint(nameCounter.Add(1), 10)
 }
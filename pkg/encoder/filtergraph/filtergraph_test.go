@@ -5,15 +5,20 @@ import (
 	"github.com/stretchr/testify/assert"
 	"strings"
 	"testing"
+	"time"
 )
 
 // Testing concat filter in isolation
 func TestAudioConcatFilter(t *testing.T) {
 	a1 := NewInput("0")
 	a2 := NewInput("1")
-	concat := NewAudioConcatFilter(a1, a2)
+	concat := NewAudioConcatFilter(DefaultAudioFormat, a1, a2)
 	builtFilter := concat.Build()
-	assert.Equal(t, fmt.Sprintf("[0][1]concat=n=2:v=0:a=1[%s];", concat.Id()), builtFilter)
+	in0, in1 := concat.inputName(0), concat.inputName(1)
+	assert.Equal(t, fmt.Sprintf(
+		"[0]aresample=44100,aformat=sample_fmts=fltp:channel_layouts=stereo[%s];"+
+			"[1]aresample=44100,aformat=sample_fmts=fltp:channel_layouts=stereo[%s];"+
+			"[%s][%s]concat=n=2:v=0:a=1[%s];", in0, in1, in0, in1, concat.Id()), builtFilter)
 }
 
 // Testing mix filter in isolation
@@ -37,7 +42,7 @@ func TestAudioMixFilterWithoutModulation(t *testing.T) {
 	a2 := NewInput("1")
 	mix := NewAudioMixFilter(a1, a2, WithoutModulation, [2]float32{1, 0.2})
 	builtFilter := mix.Build()
-	assert.Equal(t, fmt.Sprintf("[0][1]amix=weights=1.0 0.2[%s];", mix.Id()), builtFilter)
+	assert.Equal(t, fmt.Sprintf("[0][1]amix=weights=1 0.2[%s];", mix.Id()), builtFilter)
 }
 
 // Testing normalization filter in isolation
@@ -65,7 +70,7 @@ func TestNormalizationFilterSpeechnorm(t *testing.T) {
 // Testing resample filter in isolation
 func TestResampleFilter(t *testing.T) {
 	a1 := NewInput("0")
-	norm := NewAudioResampleFilter(a1, K44)
+	norm := NewAudioResampleFilter(a1, AudioFormat{})
 	builtFilter := norm.Build()
 	assert.Equal(t,
 		fmt.Sprintf("[0]aformat=sample_fmts=fltp:sample_rates=%s:channel_layouts=stereo[%s];", "44100", norm.Id()),
@@ -84,7 +89,7 @@ func TestCompositeAudioConcatAndMix(t *testing.T) {
 	a1 := NewInput("0")
 	a2 := NewInput("1")
 	// First, concat a1 and a2, the result will be our main channel
-	concat := NewAudioConcatFilter(a1, a2)
+	concat := NewAudioConcatFilter(DefaultAudioFormat, a1, a2)
 	// Then, define a side channel
 	a3 := NewInput("2")
 	// And mix them together
@@ -97,5 +102,429 @@ func TestCompositeAudioConcatAndMix(t *testing.T) {
 	assert.Greater(t, mixIndex, concatIndex)
 	// concat Id should appear twice. Once as a result for the concat filter, and one more time as an input for
 	// the mix filter
-	assert.Equal(t, 2, strings.Count(builtFilter, concat.Id()))
+	assert.Equal(t, 2, strings.Count(builtFilter, "["+concat.Id()+"]"))
+}
+
+func TestNormalizationFilterLoudnormTargets(t *testing.T) {
+	a1 := NewInput("0")
+	norm := NewLoudnormFilter(a1, BroadcastLoudness(), nil)
+	builtFilter := norm.Build()
+	assert.Equal(t, fmt.Sprintf("[0]loudnorm=I=-23:TP=-1:LRA=7[%s];", norm.Id()), builtFilter)
+}
+
+func TestNormalizationFilterLoudnormAnalysis(t *testing.T) {
+	a1 := NewInput("0")
+	norm := NewLoudnormAnalysisFilter(a1, PodcastLoudness())
+	builtFilter := norm.Build()
+	assert.Equal(t, fmt.Sprintf("[0]loudnorm=I=-16:TP=-1.5:LRA=11:print_format=json[%s];", norm.Id()), builtFilter)
+}
+
+func TestNormalizationFilterLoudnormSecondPass(t *testing.T) {
+	a1 := NewInput("0")
+	measured := &LoudnormMeasurement{I: -27.61, TP: -4.47, LRA: 18.06, Thresh: -39.2, Offset: 0.58}
+	norm := NewLoudnormFilter(a1, PodcastLoudness(), measured)
+	builtFilter := norm.Build()
+	assert.Equal(t,
+		fmt.Sprintf("[0]loudnorm=I=-16:TP=-1.5:LRA=11:measured_I=-27.61:measured_TP=-4.47:measured_LRA=18.06:measured_thresh=-39.2:offset=0.58:linear=true[%s];", norm.Id()),
+		builtFilter)
+}
+
+// Testing a mix of more than two inputs
+func TestAudioMultiMixFilter(t *testing.T) {
+	inputs := []Filter{NewInput("0"), NewInput("1"), NewInput("2")}
+	mix := NewAudioMultiMixFilter(inputs, []float32{1, 0.5, 1})
+	builtFilter := mix.Build()
+	assert.Equal(t, fmt.Sprintf("[0][1][2]amix=inputs=3:weights=1 0.5 1[%s];", mix.Id()), builtFilter)
+}
+
+// Weights must not be rounded, a low weight would otherwise mute its track
+func TestAudioMixFilter_WeightsPrecision(t *testing.T) {
+	mix := NewAudioMultiMixFilter([]Filter{NewInput("0"), NewInput("1"), NewInput("2")}, []float32{0.25, 0.04, 0})
+	assert.Contains(t, mix.Build(), "weights=0.25 0.04 0[")
+}
+
+func TestAudioPassFilter(t *testing.T) {
+	a1 := NewInput("0")
+	hp := NewAudioPassFilter(a1, Highpass, 100)
+	lp := NewAudioPassFilter(hp, Lowpass, 8000)
+	builtFilter := lp.Build()
+	assert.Equal(t, fmt.Sprintf("[0]highpass=f=100[%s];[%s]lowpass=f=8000[%s];", hp.Id(), hp.Id(), lp.Id()), builtFilter)
+}
+
+// Zero values should be replaced by defaults
+func TestAudioDenoiseFilter(t *testing.T) {
+	a1 := NewInput("0")
+	dn := NewAudioDenoiseFilter(a1, DenoiseParams{Reduction: 20})
+	assert.Equal(t, fmt.Sprintf("[0]afftdn=nr=20:nf=-50[%s];", dn.Id()), dn.Build())
+}
+
+func TestAudioCompressorFilter(t *testing.T) {
+	a1 := NewInput("0")
+	comp := NewAudioCompressorFilter(a1, CompressorParams{Ratio: 4, Makeup: 2})
+	assert.Equal(t,
+		fmt.Sprintf("[0]acompressor=threshold=-18dB:ratio=4:attack=20:release=250:makeup=2dB[%s];", comp.Id()),
+		comp.Build())
+}
+
+func TestAudioGateFilter(t *testing.T) {
+	a1 := NewInput("0")
+	gate := NewAudioGateFilter(a1, GateParams{})
+	assert.Equal(t, fmt.Sprintf("[0]agate=threshold=-40dB:ratio=2:attack=10:release=250[%s];", gate.Id()), gate.Build())
+}
+
+func TestGainFilter(t *testing.T) {
+	a1 := NewInput("0")
+	gain := NewAudioGainFilter(a1, -3.5)
+	assert.Equal(t, fmt.Sprintf("[0]volume=-3.5dB[%s];", gain.Id()), gain.Build())
+}
+
+func TestSilenceDetectFilter(t *testing.T) {
+	a1 := NewInput("0")
+	sd := NewSilenceDetectFilter(a1, -50, 1500*time.Millisecond)
+	assert.Equal(t, fmt.Sprintf("[0]silencedetect=n=-50dB:d=1.5[%s];", sd.Id()), sd.Build())
+}
+
+func TestAudioCutFilter(t *testing.T) {
+	a1 := NewInput("0")
+	cut := NewAudioCutFilter(a1, []TimeRange{
+		{Start: 0, End: 2500 * time.Millisecond},
+		{Start: 10 * time.Second, End: 12 * time.Second},
+	})
+	assert.Equal(t,
+		fmt.Sprintf("[0]aselect='not(between(t,0,2.5)+between(t,10,12))',asetpts=N/SR/TB[%s];", cut.Id()),
+		cut.Build())
+}
+
+func TestVideoCutFilter(t *testing.T) {
+	cut := NewVideoCutFilter(NewInput("0:v"), []TimeRange{{Start: 0, End: 2500 * time.Millisecond}})
+	assert.Equal(t,
+		fmt.Sprintf("[0:v]select='not(between(t,0,2.5))',setpts=N/FRAME_RATE/TB[%s];", cut.Id()),
+		cut.Build())
+}
+
+func TestEscapeValue(t *testing.T) {
+	assert.Equal(t, `\'/tmp/a b.srt\'`, EscapeValue("/tmp/a b.srt"))
+	assert.Equal(t, `\'C:/it\'\\\'\'s\,here\'`, EscapeValue("C:/it's,here"))
+}
+
+func TestSubtitlesFilter(t *testing.T) {
+	v := NewInput("0:v")
+	subs := NewSubtitlesFilter(v, "/tmp/sub.srt", SubtitleStyle{})
+	assert.Equal(t, fmt.Sprintf(`[0:v]subtitles=filename=\'/tmp/sub.srt\'[%s];`, subs.Id()), subs.Build())
+}
+
+func TestSubtitlesFilterStyle(t *testing.T) {
+	v := NewInput("0:v")
+	subs := NewSubtitlesFilter(v, "/tmp/sub.srt", SubtitleStyle{FontName: "Arial", FontSize: 24, PrimaryColor: "#ff8000", Bold: true})
+	assert.Equal(t,
+		fmt.Sprintf(`[0:v]subtitles=filename=\'/tmp/sub.srt\':force_style=\'FontName=Arial\,FontSize=24\,PrimaryColour=&H0080FF\,Bold=1\'[%s];`, subs.Id()),
+		subs.Build())
+	assert.Equal(t, "OutlineColour=&H112233", (&SubtitleStyle{OutlineColor: "0x332211"}).forceStyle())
+}
+
+func TestAudioVisualizationFilter(t *testing.T) {
+	a := NewInput("1")
+	bg := NewInput("0:v")
+	vis := NewAudioVisualizationFilter(a, bg, VisualizationParams{})
+	id := vis.Id()
+	assert.Equal(t,
+		fmt.Sprintf("[1]asplit[%s][%s_in];[%s_in]showwaves=s=1280x240:mode=cline:colors=white[%s_wave];[0:v][%s_wave]overlay=x=(W-w)/2:y=(H-h)/2:shortest=1[%s];",
+			vis.Audio().Id(), id, id, id, id, id),
+		vis.Build())
+	assert.Equal(t, "", vis.Audio().Build())
+}
+
+func TestAudioVisualizationFilterModes(t *testing.T) {
+	a := NewInput("1")
+	bg := NewInput("0:v")
+	spectrum := NewAudioVisualizationFilter(a, bg, VisualizationParams{Mode: Spectrum, Width: 640, Height: 360, Position: Bottom})
+	assert.Contains(t, spectrum.Build(), "showspectrum=s=640x360:mode=combined:slide=scroll:color=intensity")
+	assert.Contains(t, spectrum.Build(), "overlay=x=(W-w)/2:y=H-h:shortest=1")
+	freqs := NewAudioVisualizationFilter(a, bg, VisualizationParams{Mode: Frequencies, Color: "#ff0000", Position: Top})
+	assert.Contains(t, freqs.Build(), "showfreqs=s=1280x240:mode=bar:colors=#ff0000")
+	assert.Contains(t, freqs.Build(), "y=0:")
+}
+
+func TestIsColor(t *testing.T) {
+	for _, c := range []string{"white", "#FF8000", "0xff8000", "#ff800080", "black@0.5"} {
+		assert.True(t, IsColor(c), c)
+	}
+	for _, c := range []string{"", "#ff80", "red:x=1", "white];[0"} {
+		assert.False(t, IsColor(c), c)
+	}
+}
+
+func TestDrawTextFilter(t *testing.T) {
+	v := NewInput("0:v")
+	text := NewDrawTextFilter(v, TextParams{Text: "Episode 1", FontFile: "/fonts/a.ttf"})
+	assert.Equal(t,
+		fmt.Sprintf(`[0:v]drawtext=fontfile=\'/fonts/a.ttf\':text=\'Episode 1\':fontsize=36:fontcolor=white:x=(w-tw)/2:y=h-th-20[%s];`, text.Id()),
+		text.Build())
+}
+
+func TestDrawTextFilterOptions(t *testing.T) {
+	v := NewInput("0:v")
+	text := NewDrawTextFilter(v, TextParams{
+		Text:     "It's 100% {timestamp}",
+		FontFile: "/fonts/a.ttf",
+		FontSize: 24,
+		Color:    "#ff0000",
+		BoxColor: "black@0.5",
+		Position: TopRight,
+		Start:    time.Second,
+		End:      5500 * time.Millisecond,
+	})
+	assert.Equal(t,
+		fmt.Sprintf(`[0:v]drawtext=fontfile=\'/fonts/a.ttf\':text=\'It\'\\\'\'s 100\\%% %%{pts:hms}\':fontsize=24:fontcolor=#ff0000:x=w-tw-20:y=20:box=1:boxcolor=black@0.5:boxborderw=10:enable=\'between(t\,1\,5.5)\'[%s];`, text.Id()),
+		text.Build())
+}
+
+func TestDrawTextFilterOpenEnded(t *testing.T) {
+	v := NewInput("0:v")
+	text := NewDrawTextFilter(v, TextParams{Text: "a", FontFile: "f.ttf", Start: 2 * time.Second})
+	assert.Contains(t, text.Build(), `:enable=\'gte(t\,2)\'`)
+}
+
+func TestVideoNormalizeFilter(t *testing.T) {
+	v := NewInput("0:v")
+	norm := NewVideoNormalizeFilter(v, DefaultCanvas)
+	assert.Equal(t,
+		fmt.Sprintf("[0:v]scale=w=1280:h=720:force_original_aspect_ratio=decrease:force_divisible_by=2,pad=w=1280:h=720:x=(ow-iw)/2:y=(oh-ih)/2,setsar=1,fps=25,format=yuv420p[%s];", norm.Id()),
+		norm.Build())
+}
+
+func TestVideoNormalizeFilterCrop(t *testing.T) {
+	v := NewInput("0:v")
+	norm := NewVideoNormalizeFilter(v, Canvas{Width: 1080, Height: 1920, FrameRate: 30, Fit: Crop})
+	assert.Equal(t,
+		fmt.Sprintf("[0:v]scale=w=1080:h=1920:force_original_aspect_ratio=increase,crop=w=1080:h=1920,setsar=1,fps=30,format=yuv420p[%s];", norm.Id()),
+		norm.Build())
+}
+
+func TestVideoNormalizeFilterBlurPad(t *testing.T) {
+	v := NewInput("0:v")
+	norm := NewVideoNormalizeFilter(v, Canvas{Width: 1080, Height: 1920, FrameRate: 30, Fit: BlurPad})
+	id := norm.Id()
+	assert.Equal(t,
+		fmt.Sprintf("[0:v]split[%s_in_bg][%s_in_fg];"+
+			"[%s_in_bg]scale=w=1080:h=1920:force_original_aspect_ratio=increase,crop=w=1080:h=1920,boxblur=20:5[%s_bg];"+
+			"[%s_in_fg]scale=w=1080:h=1920:force_original_aspect_ratio=decrease:force_divisible_by=2[%s_fg];"+
+			"[%s_bg][%s_fg]overlay=x=(W-w)/2:y=(H-h)/2,setsar=1,fps=30,format=yuv420p[%s];",
+			id, id, id, id, id, id, id, id, id),
+		norm.Build())
+}
+
+func TestVideoEvenSizeFilter(t *testing.T) {
+	v := NewInput("0:v")
+	even := NewVideoEvenSizeFilter(v)
+	assert.Equal(t, fmt.Sprintf("[0:v]scale=w=trunc(iw/2)*2:h=trunc(ih/2)*2[%s];", even.Id()), even.Build())
+}
+
+func TestTrimFilter(t *testing.T) {
+	v := NewInput("0:v")
+	trim := NewTrimFilter(v, 12500*time.Millisecond)
+	assert.Equal(t, fmt.Sprintf("[0:v]trim=duration=12.5,setpts=PTS-STARTPTS[%s];", trim.Id()), trim.Build())
+	a := NewInput("1")
+	atrim := NewAudioTrimFilter(a, 3*time.Second)
+	assert.Equal(t, fmt.Sprintf("[1]atrim=duration=3,asetpts=PTS-STARTPTS[%s];", atrim.Id()), atrim.Build())
+}
+
+func TestConcatFilter(t *testing.T) {
+	concat := NewConcatFilter(
+		Segment{Video: NewInput("0:v"), Audio: NewInput("0:a")},
+		Segment{Video: NewInput("1:v"), Audio: NewInput("1:a")},
+	)
+	assert.Equal(t,
+		fmt.Sprintf("[0:v][0:a][1:v][1:a]concat=n=2:v=1:a=1[%s][%s];", concat.Id(), concat.Audio().Id()),
+		concat.Build())
+	assert.Equal(t, "", concat.Audio().Build())
+}
+
+func TestXfadeFilter(t *testing.T) {
+	xfade := NewXfadeFilter(NewInput("0:v"), NewInput("1:v"), "fade", time.Second, 4500*time.Millisecond)
+	assert.Equal(t, fmt.Sprintf("[0:v][1:v]xfade=transition=fade:duration=1:offset=4.5[%s];", xfade.Id()), xfade.Build())
+	assert.True(t, IsTransition("wipeleft"))
+	assert.False(t, IsTransition("custom"))
+}
+
+func TestKenBurnsFilter(t *testing.T) {
+	zoom := NewKenBurnsFilter(NewInput("0:v"), DefaultCanvas, 5*time.Second)
+	assert.Equal(t,
+		fmt.Sprintf(`[0:v]zoompan=z=\'min(zoom+0.0016\,1.2)\':x=\'iw/2-(iw/zoom/2)\':y=\'ih/2-(ih/zoom/2)\':d=125:s=1280x720:fps=25,setsar=1[%s];`, zoom.Id()),
+		zoom.Build())
+}
+
+func TestVideoConcatFilter(t *testing.T) {
+	concat := NewVideoConcatFilter(NewInput("0:v"), NewInput("1:v"))
+	assert.Equal(t, fmt.Sprintf("[0:v][1:v]concat=n=2:v=1:a=0[%s];", concat.Id()), concat.Build())
+}
+
+func TestThumbnailFilter(t *testing.T) {
+	thumb := NewThumbnailFilter(NewInput("0:v"), 36*time.Second, 100)
+	assert.Equal(t, fmt.Sprintf("[0:v]fps=fps=1/36,thumbnail=n=100[%s];", thumb.Id()), thumb.Build())
+}
+
+func TestSpriteFilter(t *testing.T) {
+	sprite := NewSpriteFilter(NewInput("0:v"), SpriteLayout{Interval: 10 * time.Second, Width: 160, Height: 90, Columns: 10, Rows: 5})
+	assert.Equal(t,
+		fmt.Sprintf("[0:v]fps=fps=1/10,scale=w=160:h=90:force_original_aspect_ratio=decrease:force_divisible_by=2,pad=w=160:h=90:x=(ow-iw)/2:y=(oh-ih)/2,tile=layout=10x5[%s];", sprite.Id()),
+		sprite.Build())
+}
+
+func TestPreviewFilter(t *testing.T) {
+	preview := NewPreviewFilter(NewInput("0:v"), 480, 12, false)
+	assert.Equal(t, fmt.Sprintf("[0:v]fps=fps=12,scale=w=480:h=-2:flags=lanczos[%s];", preview.Id()), preview.Build())
+}
+
+func TestPreviewFilter_Palette(t *testing.T) {
+	preview := NewPreviewFilter(NewInput("0:v"), 480, 12, true)
+	id := preview.Id()
+	assert.Equal(t,
+		fmt.Sprintf("[0:v]fps=fps=12,scale=w=480:h=-2:flags=lanczos,split[%[1]s_s0][%[1]s_s1];[%[1]s_s0]palettegen[%[1]s_pal];[%[1]s_s1][%[1]s_pal]paletteuse[%[1]s];", id),
+		preview.Build())
+}
+
+func TestResampleFilter_Format(t *testing.T) {
+	norm := NewAudioResampleFilter(NewInput("0"), AudioFormat{SampleRate: K48, SampleFormat: S16, ChannelLayout: Mono})
+	assert.Equal(t, fmt.Sprintf("[0]aformat=sample_fmts=s16:sample_rates=48000:channel_layouts=mono[%s];", norm.Id()), norm.Build())
+}
+
+func TestAudioConcatFilter_Format(t *testing.T) {
+	concat := NewAudioConcatFilter(AudioFormat{ChannelLayout: Mono}, NewInput("0"), NewInput("1"))
+	assert.Contains(t, concat.Build(), fmt.Sprintf("[1]aresample=44100,aformat=sample_fmts=fltp:channel_layouts=mono[%s];", concat.inputName(1)))
+}
+
+func TestChannelLayout_Channels(t *testing.T) {
+	assert.Equal(t, 1, Mono.Channels())
+	assert.Equal(t, 2, Stereo.Channels())
+	assert.Equal(t, 6, Surround51.Channels())
+}
+
+func TestGraph_DeterministicLabels(t *testing.T) {
+	build := func() string {
+		concat := NewAudioConcatFilter(DefaultAudioFormat, NewInput("0"), NewInput("1"))
+		mix := NewAudioMixFilter(concat, NewInput("2"), WithoutModulation, [2]float32{1, 0.5})
+		graph, err := NewGraph(3, mix).Build()
+		assert.NoError(t, err)
+		return graph
+	}
+	expected := "[0]aresample=44100,aformat=sample_fmts=fltp:channel_layouts=stereo[concat3_in0];" +
+		"[1]aresample=44100,aformat=sample_fmts=fltp:channel_layouts=stereo[concat3_in1];" +
+		"[concat3_in0][concat3_in1]concat=n=2:v=0:a=1[concat3];" +
+		"[concat3][2]amix=weights=1 0.5[mixed5]"
+	// Labels only depend on the graph structure
+	assert.Equal(t, expected, build())
+	assert.Equal(t, expected, build())
+}
+
+func TestGraph_LabelsKeptByGraph(t *testing.T) {
+	concat := NewAudioConcatFilter(DefaultAudioFormat, NewInput("0"), NewInput("1"))
+	id := concat.Id()
+	g := NewGraph(2, concat)
+	_, err := g.Build()
+	assert.NoError(t, err)
+	assert.Equal(t, "concat3", g.Label(concat))
+	// The node keeps its own name, and can still be built on its own or in another graph
+	assert.Equal(t, id, concat.Id())
+	assert.Contains(t, concat.Build(), "["+id+"]")
+	other, err := NewGraph(2, NewAudioVolumeFilter(concat, 2)).Build()
+	assert.NoError(t, err)
+	assert.Contains(t, other, "[concat3]volume=2.00[vol4]")
+}
+
+func TestGraph_SharedNodeEmittedOnce(t *testing.T) {
+	vis := NewAudioVisualizationFilter(NewInput("1"), NewInput("0:v"), VisualizationParams{})
+	graph, err := NewGraph(2, vis, vis.Audio()).Build()
+	assert.NoError(t, err)
+	assert.Equal(t, 1, strings.Count(graph, "asplit"))
+	assert.Equal(t, 1, strings.Count(graph, "overlay"))
+	assert.False(t, strings.HasSuffix(graph, ";"))
+}
+
+func TestGraph_Split(t *testing.T) {
+	split := NewSplitFilter(NewInput("0:v"), 2)
+	left := NewVideoNormalizeFilter(split.Output(0), Canvas{Width: 1280, Height: 720, FrameRate: 25})
+	right := NewVideoEvenSizeFilter(split.Output(1))
+	g := NewGraph(1, left, right)
+	graph, err := g.Build()
+	assert.NoError(t, err)
+	assert.Equal(t, 1, strings.Count(graph, "split=2"))
+	assert.Contains(t, graph, fmt.Sprintf("[0:v]split=2[%s][%s];", g.Label(split), g.Label(split.Output(1))))
+	assert.Contains(t, graph, fmt.Sprintf("[%s]scale=w=trunc(iw/2)*2", g.Label(split.Output(1))))
+}
+
+func TestGraph_AudioSplit(t *testing.T) {
+	split := NewAudioSplitFilter(NewInput("0"), 3)
+	assert.Equal(t,
+		fmt.Sprintf("[0]asplit=3[%[1]s][%[1]s_1][%[1]s_2];", split.Id()),
+		split.Build())
+}
+
+func TestGraph_PadUsedTwice(t *testing.T) {
+	vol := NewAudioVolumeFilter(NewInput("0"), 1.5)
+	a := NewAudioNormalizationFilter(vol, Dynaudnorm)
+	b := NewAudioNormalizationFilter(vol, Speechnorm)
+	_, err := NewGraph(1, a, b).Build()
+	assert.ErrorContains(t, err, "must be split first")
+}
+
+func TestGraph_UnusedPad(t *testing.T) {
+	vis := NewAudioVisualizationFilter(NewInput("1"), NewInput("0:v"), VisualizationParams{})
+	// The audio output of the visualization is never used
+	_, err := NewGraph(2, vis).Build()
+	assert.ErrorContains(t, err, "produced but never used")
+}
+
+func TestGraph_DanglingPad(t *testing.T) {
+	// An input label which is neither an FFmpeg stream nor produced by any filter
+	_, err := NewGraph(1, NewAudioVolumeFilter(NewInput("missing"), 2)).Build()
+	assert.ErrorContains(t, err, "[missing] is used but never produced")
+}
+
+func TestGraph_UnknownInput(t *testing.T) {
+	_, err := NewGraph(1, NewAudioVolumeFilter(NewInput("3:a"), 2)).Build()
+	assert.ErrorContains(t, err, "references input 3")
+}
+
+func TestGraph_QuotedSeparator(t *testing.T) {
+	text := NewDrawTextFilter(NewInput("0:v"), TextParams{Text: "a;b[c]", FontFile: "/fonts/a.ttf"})
+	_, err := NewGraph(1, text).Build()
+	assert.NoError(t, err)
+}
+
+func TestRawFilter(t *testing.T) {
+	echo := NewRawFilter("aecho", []RawOption{{Key: "in_gain", Value: "0.8"}, {Key: "delays", Value: "1000|1800"}}, 1, NewInput("0"))
+	assert.Equal(t, fmt.Sprintf(`[0]aecho=in_gain=\'0.8\':delays=\'1000|1800\'[%s];`, echo.Id()), echo.Build())
+	// Positional options, and no option at all
+	assert.Contains(t, NewRawFilter("pan", []RawOption{{Value: "mono|c0=FL"}}, 1, NewInput("0")).Build(), `[0]pan=\'mono|c0=FL\'[`)
+	assert.Contains(t, NewRawFilter("hflip", nil, 1, NewInput("0:v")).Build(), "[0:v]hflip[")
+}
+
+func TestRawFilter_Escaping(t *testing.T) {
+	// A value can't break out of the filter
+	text := NewRawFilter("drawtext", []RawOption{{Key: "text", Value: "a'b;c[d],e:f"}}, 1, NewInput("0:v"))
+	graph, err := NewGraph(1, text).Build()
+	assert.NoError(t, err)
+	// The whole value stays in the single chain of the filter
+	assert.Equal(t, fmt.Sprintf("[0:v]drawtext=text=%s[raw2]", EscapeValue("a'b;c[d],e:f")), graph)
+}
+
+func TestRawFilter_MultipleInputsOutputs(t *testing.T) {
+	merge := NewRawFilter("amerge", []RawOption{{Key: "inputs", Value: "2"}}, 1, NewInput("0"), NewInput("1"))
+	assert.Contains(t, merge.Build(), "[0][1]amerge=")
+	split := NewRawFilter("channelsplit", nil, 2, merge)
+	g := NewGraph(2, split.Output(0), split.Output(1))
+	graph, err := g.Build()
+	assert.NoError(t, err)
+	assert.Contains(t, graph, fmt.Sprintf("channelsplit[%s][%s]", g.Label(split), g.Label(split.Output(1))))
+}
+
+func TestRawFilter_Names(t *testing.T) {
+	assert.True(t, IsValidFilterName("colorchannelmixer"))
+	assert.True(t, IsValidFilterName("lut3d"))
+	for _, name := range []string{"", "aecho,movie", "a b", "[0]aecho", "Aecho"} {
+		assert.False(t, IsValidFilterName(name), name)
+	}
+	assert.True(t, IsValidOptionKey("in_gain"))
+	assert.False(t, IsValidOptionKey("in_gain=1:out_gain"))
 }
@@ -0,0 +1,212 @@
+package filtergraph
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Graph A complete filtergraph, made of one or more independent outputs (roots).
+// Nodes reachable from multiple roots, or from multiple outputs of the same filter, are emitted once,
+// after all the nodes they depend on. All nodes are labelled deterministically, in dependency order,
+// so that the same graph always produces the same string
+type Graph struct {
+	// Number of FFmpeg inputs the graph can use. 0 if unknown
+	inputCount int
+	roots      []Filter
+	// All filters reachable from the roots, in dependency order
+	filters []Filter
+	// Label of each filter node, as used in the built filtergraph. Inputs and secondary outputs are not
+	// labelled by the graph : inputs are named by FFmpeg, and secondary outputs by the filter they belong to
+	labels map[*Node]string
+	// Nodes already emitted during the current Build
+	emitted map[*Node]bool
+}
+
+// Multi-outputs filters, producing secondary outputs besides their own. Each secondary output
+// is identified by its suffix, as given to newOutputFilter
+type multiOutputFilter interface {
+	secondaryOutputs() []string
+}
+
+// NewGraph Return a graph producing all roots, using inputCount FFmpeg inputs
+func NewGraph(inputCount int, roots ...Filter) *Graph {
+	return &Graph{inputCount: inputCount, roots: roots}
+}
+
+// Build Resolve the graph into a string usable in FFMPEG -filter_complex option.
+// An error is returned if a pad is used but never produced, produced but never used, or used multiple times
+func (g *Graph) Build() (string, error) {
+	g.label()
+	if err := g.validate(); err != nil {
+		return "", err
+	}
+	ss := strings.Builder{}
+	g.attach(func() {
+		g.emitted = map[*Node]bool{}
+		for _, root := range g.roots {
+			if g.emitted[root.node()] {
+				continue
+			}
+			g.emitted[root.node()] = true
+			ss.WriteString(root.Build())
+		}
+	})
+	// Remove the last ";" in the last step of the filtergraph. That's how FFmpeg knows it's complete
+	return strings.TrimSuffix(ss.String(), ";"), nil
+}
+
+// Label Return the label of the filter in the built filtergraph, such as "norm2".
+// A filter which is not part of the graph keeps its own Id
+func (g *Graph) Label(f Filter) string {
+	g.label()
+	var label string
+	g.attach(func() {
+		label = f.Id()
+	})
+	return label
+}
+
+// Return the label allocated to the node, if it is part of the graph
+func (g *Graph) labelOf(n *Node) (string, bool) {
+	if g == nil {
+		return "", false
+	}
+	label, labelled := g.labels[n]
+	return label, labelled
+}
+
+// Attach all nodes of the graph to it while running fn, so that their Id is their label in the graph.
+// Once done, nodes can be built on their own again, or be part of another graph
+func (g *Graph) attach(fn func()) {
+	for _, f := range g.filters {
+		f.node().graph = g
+	}
+	defer func() {
+		for _, f := range g.filters {
+			f.node().graph = nil
+		}
+	}()
+	fn()
+}
+
+// List all filters reachable from the roots in dependency order, and label them in this order.
+// Labels are only allocated once, a graph always keeps the same labels
+func (g *Graph) label() {
+	if g.labels != nil {
+		return
+	}
+	g.labels = map[*Node]string{}
+	visited := map[*Node]bool{}
+	var visit func(f Filter)
+	visit = func(f Filter) {
+		n := f.node()
+		if visited[n] {
+			return
+		}
+		visited[n] = true
+		for _, c := range n.children {
+			visit(c)
+		}
+		g.filters = append(g.filters, f)
+		switch f.(type) {
+		case *InputFilter, *outputFilter:
+		default:
+			g.labels[n] = fmt.Sprintf("%s%d", kind(n.name), len(g.filters))
+		}
+	}
+	for _, root := range g.roots {
+		visit(root)
+	}
+}
+
+// Kind of node, being the name without its unique part ("concat_12" -> "concat")
+func kind(name string) string {
+	if i := strings.LastIndex(name, "_"); i >= 0 {
+		return name[:i]
+	}
+	return name
+}
+
+// Pads referencing an FFmpeg input stream : "0", "1:a", "0:v:0"...
+var inputPadRegex = regexp.MustCompile(`^(\d+)(:.+)?$`)
+
+// An output of a filter : its own output, or the secondary output with the given suffix
+type pad struct {
+	node   *Node
+	suffix string
+}
+
+// Return the pad a filter reads from, when used as an input of another filter
+func padOf(f Filter) pad {
+	if of, isOutput := f.(*outputFilter); isOutput {
+		return pad{of.children[0].node(), of.suffix}
+	}
+	return pad{node: f.node()}
+}
+
+// Label of the pad in the built filtergraph
+func (g *Graph) padLabel(p pad) string {
+	if p.suffix == "" {
+		return g.labels[p.node]
+	}
+	return fmt.Sprintf("%s_%s", g.labels[p.node], p.suffix)
+}
+
+// Check that each pad of the graph is produced once and consumed once, except the roots
+// which are consumed by the outputs, and the input streams which can be consumed multiple times
+func (g *Graph) validate() error {
+	var errs []error
+	consumed := map[pad]int{}
+	inputs := map[string]bool{}
+	for _, f := range g.filters {
+		switch f := f.(type) {
+		case *InputFilter:
+			name := f.Id()
+			if inputs[name] {
+				continue
+			}
+			inputs[name] = true
+			m := inputPadRegex.FindStringSubmatch(name)
+			if m == nil {
+				errs = append(errs, fmt.Errorf("pad [%s] is used but never produced", name))
+			} else if index, _ := strconv.Atoi(m[1]); g.inputCount > 0 && index >= g.inputCount {
+				errs = append(errs, fmt.Errorf("pad [%s] references input %d, but there are only %d inputs", name, index, g.inputCount))
+			}
+		case *outputFilter:
+			// The only child of a secondary output is the filter producing it, not one of its inputs
+		default:
+			for _, c := range f.node().children {
+				consumed[padOf(c)]++
+			}
+		}
+	}
+	roots := map[pad]bool{}
+	for _, root := range g.roots {
+		roots[padOf(root)] = true
+	}
+
+	for _, f := range g.filters {
+		switch f.(type) {
+		case *InputFilter, *outputFilter:
+			continue
+		}
+		pads := []pad{{node: f.node()}}
+		if mof, isMulti := f.(multiOutputFilter); isMulti {
+			for _, suffix := range mof.secondaryOutputs() {
+				pads = append(pads, pad{f.node(), suffix})
+			}
+		}
+		for _, p := range pads {
+			switch {
+			case consumed[p] > 1:
+				errs = append(errs, fmt.Errorf("pad [%s] is used %d times, it must be split first", g.padLabel(p), consumed[p]))
+			case consumed[p] == 0 && !roots[p]:
+				errs = append(errs, fmt.Errorf("pad [%s] is produced but never used", g.padLabel(p)))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,102 @@
+package filtergraph
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// RawOption A single option of a RawFilter. An option without key is a positional one
+type RawOption struct {
+	Key   string `json:"key,omitempty"`
+	Value string `json:"value"`
+}
+
+// RawFilter Any FFmpeg filter, for needs not covered by a dedicated filter.
+// Option values are escaped, but the filter name and option keys are used as is : they must
+// be checked with IsValidFilterName and IsValidOptionKey when coming from an untrusted source.
+// Id() is the first output, and Output(i) the i-th one
+// Documentation : https://ffmpeg.org/ffmpeg-filters.html
+type RawFilter struct {
+	Node
+	// Name of the FFmpeg filter
+	filter string
+	// Options of the filter, in order
+	options []RawOption
+	// Number of outputs of the filter
+	outputs int
+}
+
+// NewRawFilter Apply the filter with the given options on all inputs, producing the given number of outputs
+func NewRawFilter(filter string, options []RawOption, outputs int, inputs ...Filter) *RawFilter {
+	if outputs < 1 {
+		outputs = 1
+	}
+	return &RawFilter{
+		Node{
+			name:     fmt.Sprintf("raw_%s", uniqueSuffix()),
+			children: inputs,
+		},
+		filter,
+		options,
+		outputs}
+}
+
+func (rf *RawFilter) Build() string {
+	// Expected format : [0][1]filter=key=value:positional[r1][r1_1]
+	ss := strings.Builder{}
+	// First let the children Build themselves
+	ss.WriteString(rf.buildChildren())
+	for _, c := range rf.children {
+		ss.WriteString(fmt.Sprintf("[%s]", c.Id()))
+	}
+	ss.WriteString(rf.filter)
+	for i, o := range rf.options {
+		separator := ":"
+		if i == 0 {
+			separator = "="
+		}
+		ss.WriteString(separator)
+		if o.Key != "" {
+			ss.WriteString(fmt.Sprintf("%s=", o.Key))
+		}
+		ss.WriteString(EscapeValue(o.Value))
+	}
+	for i := 0; i < rf.outputs; i++ {
+		ss.WriteString(fmt.Sprintf("[%s]", rf.Output(i).Id()))
+	}
+	ss.WriteString(";")
+	return ss.String()
+}
+
+func (rf *RawFilter) Id() string {
+	return rf.label()
+}
+
+// Output The i-th output of the filter, starting from 0
+func (rf *RawFilter) Output(i int) Filter {
+	if i == 0 {
+		return rf
+	}
+	return newOutputFilter(rf, fmt.Sprintf("%d", i))
+}
+
+func (rf *RawFilter) secondaryOutputs() []string {
+	return outputSuffixes(rf.outputs)
+}
+
+// FFmpeg filter names and option keys only contain these characters
+var (
+	filterNameRegex = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+	optionKeyRegex  = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9_]*$`)
+)
+
+// IsValidFilterName Return true if name can be used as is in a filtergraph as a filter name
+func IsValidFilterName(name string) bool {
+	return filterNameRegex.MatchString(name)
+}
+
+// IsValidOptionKey Return true if key can be used as is in a filtergraph as an option key
+func IsValidOptionKey(key string) bool {
+	return optionKeyRegex.MatchString(key)
+}
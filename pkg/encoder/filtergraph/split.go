@@ -0,0 +1,107 @@
+package filtergraph
+
+import (
+	"fmt"
+	"strings"
+)
+
+// SplitFilter Duplicate the target stream, so that it can be used as the input of multiple filters.
+// Each output of a filter can only be consumed once : a stream used twice must be split first.
+// Id() is the first output, and Output(i) the i-th one
+// Documentation : https://ffmpeg.org/ffmpeg-filters.html#split_002c-asplit
+type SplitFilter struct {
+	Node
+	// Number of copies
+	outputs int
+	// Split an audio stream (asplit) rather than a video one (split)
+	audio bool
+}
+
+// NewSplitFilter Duplicate the target video into the given number of outputs
+func NewSplitFilter(target Filter, outputs int) *SplitFilter {
+	return &SplitFilter{
+		Node{
+			name:     fmt.Sprintf("split_%s", uniqueSuffix()),
+			children: []Filter{target},
+		},
+		outputs,
+		false}
+}
+
+// NewAudioSplitFilter Duplicate the target audio into the given number of outputs
+func NewAudioSplitFilter(target Filter, outputs int) *SplitFilter {
+	return &SplitFilter{
+		Node{
+			name:     fmt.Sprintf("asplit_%s", uniqueSuffix()),
+			children: []Filter{target},
+		},
+		outputs,
+		true}
+}
+
+func (sf *SplitFilter) Build() string {
+	// Expected format : [0:v]split=3[r1][r1_1][r1_2]
+	ss := strings.Builder{}
+	// First let the children Build themselves
+	ss.WriteString(sf.buildChildren())
+	filterName := "split"
+	if sf.audio {
+		filterName = "asplit"
+	}
+	ss.WriteString(fmt.Sprintf("[%s]%s=%d", sf.children[0].Id(), filterName, sf.outputs))
+	for i := 0; i < sf.outputs; i++ {
+		ss.WriteString(fmt.Sprintf("[%s]", sf.Output(i).Id()))
+	}
+	ss.WriteString(";")
+	return ss.String()
+}
+
+func (sf *SplitFilter) Id() string {
+	return sf.label()
+}
+
+// Output The i-th copy of the target stream, starting from 0
+func (sf *SplitFilter) Output(i int) Filter {
+	if i == 0 {
+		return sf
+	}
+	return newOutputFilter(sf, fmt.Sprintf("%d", i))
+}
+
+func (sf *SplitFilter) secondaryOutputs() []string {
+	return outputSuffixes(sf.outputs)
+}
+
+// Suffixes of the secondary outputs of a filter producing the given number of outputs
+func outputSuffixes(outputs int) []string {
+	suffixes := make([]string, 0, outputs-1)
+	for i := 1; i < outputs; i++ {
+		suffixes = append(suffixes, fmt.Sprintf("%d", i))
+	}
+	return suffixes
+}
+
+// A NO-OP filter representing a secondary output of another filter.
+// On its own, it does not build anything : the filter it belongs to must also be built.
+// In a Graph, building it builds the filter it belongs to, which is emitted once
+// no matter how many of its outputs are used
+type outputFilter struct {
+	Node
+	// Distinguish this output from the other ones of the filter
+	suffix string
+}
+
+func newOutputFilter(parent Filter, suffix string) *outputFilter {
+	return &outputFilter{Node{children: []Filter{parent}}, suffix}
+}
+
+func (of *outputFilter) Build() string {
+	if of.graph == nil {
+		return ""
+	}
+	return of.buildChildren()
+}
+
+func (of *outputFilter) Id() string {
+	return fmt.Sprintf("%s_%s", of.children[0].Id(), of.suffix)
+}
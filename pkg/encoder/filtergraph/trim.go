@@ -0,0 +1,54 @@
+package filtergraph
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// TrimFilter Keep only the beginning of the target audio or video
+// Documentation : https://ffmpeg.org/ffmpeg-filters.html#trim
+type TrimFilter struct {
+	Node
+	// Duration to keep
+	duration time.Duration
+	// Whether the target is an audio or a video stream
+	audio bool
+}
+
+// NewTrimFilter Keep the first duration of the target video
+func NewTrimFilter(target Filter, duration time.Duration) *TrimFilter {
+	return &TrimFilter{
+		Node{
+			name:     fmt.Sprintf("trim_%s", uniqueSuffix()),
+			children: []Filter{target},
+		},
+		duration,
+		false}
+}
+
+// NewAudioTrimFilter Keep the first duration of the target audio
+func NewAudioTrimFilter(target Filter, duration time.Duration) *TrimFilter {
+	f := NewTrimFilter(target, duration)
+	f.audio = true
+	return f
+}
+
+func (tf *TrimFilter) Build() string {
+	// Expected format : [0:v]trim=duration=12.5,setpts=PTS-STARTPTS[r1]
+	ss := strings.Builder{}
+	// First let the children Build themselves
+	ss.WriteString(tf.buildChildren())
+	prefix := ""
+	if tf.audio {
+		prefix = "a"
+	}
+	// Timestamps are reset, so that the trimmed stream can be concatenated
+	ss.WriteString(fmt.Sprintf("[%s]%strim=duration=%s,%ssetpts=PTS-STARTPTS[%s];",
+		tf.children[0].Id(), prefix, formatFloat(tf.duration.Seconds()), prefix, tf.Id()))
+	return ss.String()
+}
+
+func (tf *TrimFilter) Id() string {
+	return tf.label()
+}
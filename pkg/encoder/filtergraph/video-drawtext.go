@@ -0,0 +1,140 @@
+package filtergraph
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// DrawTextFilter Draw a text over the target video
+// Documentation : https://ffmpeg.org/ffmpeg-filters.html#drawtext-1
+type DrawTextFilter struct {
+	Node
+	params TextParams
+}
+
+// TimestampPlaceholder Replaced by the current timestamp of the video (hh:mm:ss.ms) when drawn
+const TimestampPlaceholder = "{timestamp}"
+
+type TextPosition uint8
+
+const (
+	// The text is at the bottom of the video, horizontally centered. This is the default
+	BottomCenter TextPosition = iota
+	BottomLeft
+	BottomRight
+	TopLeft
+	TopCenter
+	TopRight
+	MiddleCenter
+)
+
+// TextParams Content and appearance of a text
+type TextParams struct {
+	// Text to draw. TimestampPlaceholder is replaced by the current timestamp
+	Text string
+	// Path of the font file
+	FontFile string
+	// Size of the font. Default to 36
+	FontSize int
+	// Color of the text. Default to white
+	Color string
+	// Color of the box behind the text. No box if empty
+	BoxColor string
+	Position TextPosition
+	// Time window in which the text is displayed. An End of 0 means until the end of the video
+	Start time.Duration
+	End   time.Duration
+}
+
+// Distance between the text and the edges of the video (px)
+const textMargin = 20
+
+func NewDrawTextFilter(target Filter, params TextParams) *DrawTextFilter {
+	if params.FontSize == 0 {
+		params.FontSize = 36
+	}
+	if params.Color == "" {
+		params.Color = "white"
+	}
+	return &DrawTextFilter{
+		Node{
+			name:     fmt.Sprintf("text_%s", uniqueSuffix()),
+			children: []Filter{target},
+		},
+		params}
+}
+
+func (dtf *DrawTextFilter) Build() string {
+	// Expected format : [0:v]drawtext=fontfile='font.ttf':text='Title':fontsize=36:fontcolor=white:x=(w-tw)/2:y=h-th-20[r1]
+	ss := strings.Builder{}
+	// First let the children Build themselves
+	ss.WriteString(dtf.buildChildren())
+	x, y := dtf.params.Position.coordinates()
+	ss.WriteString(fmt.Sprintf("[%s]drawtext=fontfile=%s:text=%s:fontsize=%d:fontcolor=%s:x=%s:y=%s",
+		dtf.children[0].Id(),
+		EscapeValue(dtf.params.FontFile),
+		EscapeValue(expandText(dtf.params.Text)),
+		dtf.params.FontSize,
+		dtf.params.Color,
+		x, y))
+	if dtf.params.BoxColor != "" {
+		ss.WriteString(fmt.Sprintf(":box=1:boxcolor=%s:boxborderw=10", dtf.params.BoxColor))
+	}
+	if dtf.params.Start > 0 || dtf.params.End > 0 {
+		ss.WriteString(fmt.Sprintf(":enable=%s", EscapeValue(dtf.params.enable())))
+	}
+	ss.WriteString(fmt.Sprintf("[%s];", dtf.Id()))
+	return ss.String()
+}
+
+func (dtf *DrawTextFilter) Id() string {
+	return dtf.label()
+}
+
+// drawtext expands "%{...}" sequences and backslashes, so the text must be escaped
+// before being placed in the filtergraph
+var textEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`)
+
+// Escape the text for drawtext, and expand the timestamp placeholder
+func expandText(text string) string {
+	parts := strings.Split(text, TimestampPlaceholder)
+	for i, p := range parts {
+		parts[i] = textEscaper.Replace(p)
+	}
+	return strings.Join(parts, "%{pts:hms}")
+}
+
+// Expression enabling the filter in the time window
+func (tp *TextParams) enable() string {
+	if tp.End <= 0 {
+		return fmt.Sprintf("gte(t,%s)", formatFloat(tp.Start.Seconds()))
+	}
+	return fmt.Sprintf("between(t,%s,%s)", formatFloat(tp.Start.Seconds()), formatFloat(tp.End.Seconds()))
+}
+
+// Return the x and y drawtext expressions of the position
+func (p TextPosition) coordinates() (string, string) {
+	left := fmt.Sprintf("%d", textMargin)
+	center := "(w-tw)/2"
+	right := fmt.Sprintf("w-tw-%d", textMargin)
+	top := fmt.Sprintf("%d", textMargin)
+	middle := "(h-th)/2"
+	bottom := fmt.Sprintf("h-th-%d", textMargin)
+	switch p {
+	case BottomLeft:
+		return left, bottom
+	case BottomRight:
+		return right, bottom
+	case TopLeft:
+		return left, top
+	case TopCenter:
+		return center, top
+	case TopRight:
+		return right, top
+	case MiddleCenter:
+		return center, middle
+	default:
+		return center, bottom
+	}
+}
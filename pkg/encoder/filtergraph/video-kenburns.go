@@ -0,0 +1,69 @@
+package filtergraph
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// KenBurnsFilter Turn a still image into a video slowly zooming on its center
+// Documentation : https://ffmpeg.org/ffmpeg-filters.html#zoompan
+type KenBurnsFilter struct {
+	Node
+	// Size and framerate of the resulting video
+	canvas Canvas
+	// Duration of the resulting video
+	duration time.Duration
+}
+
+// Zoom factor reached at the end of the video
+const kenBurnsMaxZoom = 1.2
+
+// NewKenBurnsFilter Zoom on target, which must be a single image already fitting in the canvas
+func NewKenBurnsFilter(target Filter, canvas Canvas, duration time.Duration) *KenBurnsFilter {
+	return &KenBurnsFilter{
+		Node{
+			name:     fmt.Sprintf("zoom_%s", uniqueSuffix()),
+			children: []Filter{target},
+		},
+		canvas,
+		duration}
+}
+
+func (kbf *KenBurnsFilter) Build() string {
+	// Expected format : [0:v]zoompan=z='min(zoom+0.0016,1.2)':x='iw/2-(iw/zoom/2)':y='ih/2-(ih/zoom/2)':d=125:s=1280x720:fps=25,setsar=1[r1]
+	ss := strings.Builder{}
+	// First let the children Build themselves
+	ss.WriteString(kbf.buildChildren())
+	// zoompan outputs d frames for each input frame
+	frames := kbf.frames()
+	step := (kenBurnsMaxZoom - 1) / float64(frames)
+	ss.WriteString(fmt.Sprintf("[%s]zoompan=z=%s:x=%s:y=%s:d=%d:s=%dx%d:fps=%d,setsar=1[%s];",
+		kbf.children[0].Id(),
+		EscapeValue(fmt.Sprintf("min(zoom+%s,%s)", formatFixed(step), formatFloat(kenBurnsMaxZoom))),
+		EscapeValue("iw/2-(iw/zoom/2)"),
+		EscapeValue("ih/2-(ih/zoom/2)"),
+		frames,
+		kbf.canvas.Width, kbf.canvas.Height,
+		kbf.canvas.FrameRate,
+		kbf.Id()))
+	return ss.String()
+}
+
+// Number of frames of the resulting video
+func (kbf *KenBurnsFilter) frames() int {
+	frames := int(kbf.duration.Seconds()*float64(kbf.canvas.FrameRate) + 0.5)
+	if frames < 1 {
+		return 1
+	}
+	return frames
+}
+
+func (kbf *KenBurnsFilter) Id() string {
+	return kbf.label()
+}
+
+// Format a small float with a fixed precision, avoiding exponents
+func formatFixed(f float64) string {
+	return strings.TrimRight(strings.TrimRight(fmt.Sprintf("%.6f", f), "0"), ".")
+}
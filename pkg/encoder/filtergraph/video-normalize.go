@@ -0,0 +1,107 @@
+package filtergraph
+
+import (
+	"fmt"
+	"strings"
+)
+
+// VideoNormalizeFilter Scale the target video to a canvas, with a constant framerate and pixel format.
+// Videos normalized to the same canvas can be concatenated
+// Documentation : https://ffmpeg.org/ffmpeg-filters.html#scale-1
+type VideoNormalizeFilter struct {
+	Node
+	canvas Canvas
+}
+
+// Canvas Resolution and framerate of a video
+type Canvas struct {
+	// Both dimensions must be even to be encoded with libx264
+	Width     int
+	Height    int
+	FrameRate int
+	// How a video with another aspect ratio fits in the canvas
+	Fit FitMode
+}
+
+type FitMode uint8
+
+const (
+	// The video is scaled down to fit in the canvas, and the remaining space is filled with black bars. This is the default
+	Letterbox FitMode = iota
+	// The video is scaled up to fill the canvas, and the overflowing parts are cropped
+	Crop
+	// Same as Letterbox, but the remaining space is filled with a blurred version of the video
+	BlurPad
+)
+
+// DefaultCanvas 720p at 25 fps
+var DefaultCanvas = Canvas{Width: 1280, Height: 720, FrameRate: 25}
+
+func NewVideoNormalizeFilter(target Filter, canvas Canvas) *VideoNormalizeFilter {
+	return &VideoNormalizeFilter{
+		Node{
+			name:     fmt.Sprintf("vnorm_%s", uniqueSuffix()),
+			children: []Filter{target},
+		},
+		canvas}
+}
+
+func (vnf *VideoNormalizeFilter) Build() string {
+	// Expected format : [0:v]scale=w=1280:h=720:force_original_aspect_ratio=decrease:force_divisible_by=2,pad=w=1280:h=720:x=(ow-iw)/2:y=(oh-ih)/2,setsar=1,fps=25,format=yuv420p[r1]
+	ss := strings.Builder{}
+	// First let the children Build themselves
+	ss.WriteString(vnf.buildChildren())
+	w, h := vnf.canvas.Width, vnf.canvas.Height
+	// Common to all fit modes
+	output := fmt.Sprintf("setsar=1,fps=%d,format=yuv420p[%s];", vnf.canvas.FrameRate, vnf.Id())
+	input := vnf.children[0].Id()
+	switch vnf.canvas.Fit {
+	case Crop:
+		ss.WriteString(fmt.Sprintf("[%s]scale=w=%d:h=%d:force_original_aspect_ratio=increase,crop=w=%d:h=%d,%s",
+			input, w, h, w, h, output))
+	case BlurPad:
+		// The video is used twice, once blurred as background, and once as is in the foreground
+		bg := fmt.Sprintf("%s_bg", vnf.Id())
+		fg := fmt.Sprintf("%s_fg", vnf.Id())
+		ss.WriteString(fmt.Sprintf("[%s]split[%s_in_bg][%s_in_fg];", input, vnf.Id(), vnf.Id()))
+		ss.WriteString(fmt.Sprintf("[%s_in_bg]scale=w=%d:h=%d:force_original_aspect_ratio=increase,crop=w=%d:h=%d,boxblur=20:5[%s];",
+			vnf.Id(), w, h, w, h, bg))
+		ss.WriteString(fmt.Sprintf("[%s_in_fg]scale=w=%d:h=%d:force_original_aspect_ratio=decrease:force_divisible_by=2[%s];",
+			vnf.Id(), w, h, fg))
+		ss.WriteString(fmt.Sprintf("[%s][%s]overlay=x=(W-w)/2:y=(H-h)/2,%s", bg, fg, output))
+	default:
+		ss.WriteString(fmt.Sprintf("[%s]scale=w=%d:h=%d:force_original_aspect_ratio=decrease:force_divisible_by=2,pad=w=%d:h=%d:x=(ow-iw)/2:y=(oh-ih)/2,%s",
+			input, w, h, w, h, output))
+	}
+	return ss.String()
+}
+
+func (vnf *VideoNormalizeFilter) Id() string {
+	return vnf.label()
+}
+
+// VideoEvenSizeFilter Shrink the target video by at most one pixel in each dimension, so that both are even.
+// Documentation : https://ffmpeg.org/ffmpeg-filters.html#scale-1
+type VideoEvenSizeFilter struct {
+	Node
+}
+
+func NewVideoEvenSizeFilter(target Filter) *VideoEvenSizeFilter {
+	return &VideoEvenSizeFilter{Node{
+		name:     fmt.Sprintf("even_%s", uniqueSuffix()),
+		children: []Filter{target},
+	}}
+}
+
+func (vef *VideoEvenSizeFilter) Build() string {
+	// Expected format : [0:v]scale=w=trunc(iw/2)*2:h=trunc(ih/2)*2[r1]
+	ss := strings.Builder{}
+	// First let the children Build themselves
+	ss.WriteString(vef.buildChildren())
+	ss.WriteString(fmt.Sprintf("[%s]scale=w=trunc(iw/2)*2:h=trunc(ih/2)*2[%s];", vef.children[0].Id(), vef.Id()))
+	return ss.String()
+}
+
+func (vef *VideoEvenSizeFilter) Id() string {
+	return vef.label()
+}
@@ -0,0 +1,52 @@
+package filtergraph
+
+import (
+	"fmt"
+	"strings"
+)
+
+// PreviewFilter Scale down the target video for a lightweight animated preview.
+// For a GIF, a palette is computed from the video itself, as the default 256 colors one looks dull
+// Documentation : https://ffmpeg.org/ffmpeg-filters.html#palettegen
+type PreviewFilter struct {
+	Node
+	// Width of the preview (px), the height keeps the aspect ratio
+	width     int
+	frameRate int
+	// Use a custom palette, for the GIF format
+	palette bool
+}
+
+func NewPreviewFilter(target Filter, width int, frameRate int, palette bool) *PreviewFilter {
+	return &PreviewFilter{
+		Node{
+			name:     fmt.Sprintf("preview_%s", uniqueSuffix()),
+			children: []Filter{target},
+		},
+		width,
+		frameRate,
+		palette}
+}
+
+func (pf *PreviewFilter) Build() string {
+	// Expected format : [0:v]fps=fps=12,scale=w=480:h=-2:flags=lanczos,split[s0][s1];[s0]palettegen[p];[s1][p]paletteuse[r1]
+	ss := strings.Builder{}
+	// First let the children Build themselves
+	ss.WriteString(pf.buildChildren())
+	scaled := fmt.Sprintf("[%s]fps=fps=%d,scale=w=%d:h=-2:flags=lanczos", pf.children[0].Id(), pf.frameRate, pf.width)
+	if !pf.palette {
+		ss.WriteString(fmt.Sprintf("%s[%s];", scaled, pf.Id()))
+		return ss.String()
+	}
+	toPalette := fmt.Sprintf("%s_s0", pf.Id())
+	toApply := fmt.Sprintf("%s_s1", pf.Id())
+	palette := fmt.Sprintf("%s_pal", pf.Id())
+	ss.WriteString(fmt.Sprintf("%s,split[%s][%s];", scaled, toPalette, toApply))
+	ss.WriteString(fmt.Sprintf("[%s]palettegen[%s];", toPalette, palette))
+	ss.WriteString(fmt.Sprintf("[%s][%s]paletteuse[%s];", toApply, palette, pf.Id()))
+	return ss.String()
+}
+
+func (pf *PreviewFilter) Id() string {
+	return pf.label()
+}
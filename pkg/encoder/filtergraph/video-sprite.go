@@ -0,0 +1,55 @@
+package filtergraph
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// SpriteFilter Tile thumbnails of the target video, taken at a regular interval, into a single image
+// Documentation : https://ffmpeg.org/ffmpeg-filters.html#tile
+type SpriteFilter struct {
+	Node
+	layout SpriteLayout
+}
+
+// SpriteLayout Size and disposition of the thumbnails in a sprite sheet
+type SpriteLayout struct {
+	// Time between two thumbnails
+	Interval time.Duration
+	// Size of a single thumbnail. The video is letterboxed to fit in it
+	Width  int
+	Height int
+	// Number of thumbnails in each row and column of the sheet
+	Columns int
+	Rows    int
+}
+
+func NewSpriteFilter(target Filter, layout SpriteLayout) *SpriteFilter {
+	return &SpriteFilter{
+		Node{
+			name:     fmt.Sprintf("sprite_%s", uniqueSuffix()),
+			children: []Filter{target},
+		},
+		layout}
+}
+
+func (sf *SpriteFilter) Build() string {
+	// Expected format : [0:v]fps=fps=1/10,scale=w=160:h=90:force_original_aspect_ratio=decrease:force_divisible_by=2,pad=w=160:h=90:x=(ow-iw)/2:y=(oh-ih)/2,tile=layout=10x5[r1]
+	ss := strings.Builder{}
+	// First let the children Build themselves
+	ss.WriteString(sf.buildChildren())
+	l := sf.layout
+	ss.WriteString(fmt.Sprintf("[%s]fps=fps=1/%s,scale=w=%d:h=%d:force_original_aspect_ratio=decrease:force_divisible_by=2,pad=w=%d:h=%d:x=(ow-iw)/2:y=(oh-ih)/2,tile=layout=%dx%d[%s];",
+		sf.children[0].Id(),
+		formatFloat(l.Interval.Seconds()),
+		l.Width, l.Height,
+		l.Width, l.Height,
+		l.Columns, l.Rows,
+		sf.Id()))
+	return ss.String()
+}
+
+func (sf *SpriteFilter) Id() string {
+	return sf.label()
+}
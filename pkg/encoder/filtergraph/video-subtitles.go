@@ -0,0 +1,105 @@
+package filtergraph
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// SubtitlesFilter Burn subtitles (SRT, WebVTT, ASS...) into the target video
+// Documentation : https://ffmpeg.org/ffmpeg-filters.html#subtitles-1
+type SubtitlesFilter struct {
+	Node
+	// Path of the subtitles file
+	path string
+	// Style overriding the subtitles default style
+	style SubtitleStyle
+}
+
+// SubtitleStyle Appearance of burnt subtitles. Zero values keep the libass defaults
+type SubtitleStyle struct {
+	// Name of the font to use
+	FontName string `json:"fontName"`
+	// Size of the font
+	FontSize int `json:"fontSize"`
+	// Text color, as #RRGGBB
+	PrimaryColor string `json:"primaryColor"`
+	// Outline color, as #RRGGBB
+	OutlineColor string `json:"outlineColor"`
+	// Outline width (px)
+	Outline int `json:"outline"`
+	// Distance from the bottom of the video (px)
+	MarginV int `json:"marginV"`
+	// Position of the subtitles, using the numpad layout (2 is bottom center)
+	Alignment int `json:"alignment"`
+	// Use a bold font
+	Bold bool `json:"bold"`
+}
+
+func NewSubtitlesFilter(target Filter, path string, style SubtitleStyle) *SubtitlesFilter {
+	return &SubtitlesFilter{
+		Node{
+			name:     fmt.Sprintf("subs_%s", uniqueSuffix()),
+			children: []Filter{target},
+		},
+		path,
+		style}
+}
+
+func (sf *SubtitlesFilter) Build() string {
+	// Expected format : [0:v]subtitles=filename='sub.srt':force_style='FontName=Arial,FontSize=24'[r1]
+	ss := strings.Builder{}
+	// First let the children Build themselves
+	ss.WriteString(sf.buildChildren())
+	ss.WriteString(fmt.Sprintf("[%s]subtitles=filename=%s", sf.children[0].Id(), EscapeValue(sf.path)))
+	if forceStyle := sf.style.forceStyle(); forceStyle != "" {
+		ss.WriteString(fmt.Sprintf(":force_style=%s", EscapeValue(forceStyle)))
+	}
+	ss.WriteString(fmt.Sprintf("[%s];", sf.Id()))
+	return ss.String()
+}
+
+func (sf *SubtitlesFilter) Id() string {
+	return sf.label()
+}
+
+// Convert the style into an ASS style override
+func (st *SubtitleStyle) forceStyle() string {
+	var fields []string
+	if st.FontName != "" {
+		fields = append(fields, fmt.Sprintf("FontName=%s", st.FontName))
+	}
+	if st.FontSize != 0 {
+		fields = append(fields, fmt.Sprintf("FontSize=%d", st.FontSize))
+	}
+	if st.PrimaryColor != "" {
+		fields = append(fields, fmt.Sprintf("PrimaryColour=%s", assColor(st.PrimaryColor)))
+	}
+	if st.OutlineColor != "" {
+		fields = append(fields, fmt.Sprintf("OutlineColour=%s", assColor(st.OutlineColor)))
+	}
+	if st.Outline != 0 {
+		fields = append(fields, fmt.Sprintf("Outline=%d", st.Outline))
+	}
+	if st.MarginV != 0 {
+		fields = append(fields, fmt.Sprintf("MarginV=%d", st.MarginV))
+	}
+	if st.Alignment != 0 {
+		fields = append(fields, fmt.Sprintf("Alignment=%d", st.Alignment))
+	}
+	if st.Bold {
+		fields = append(fields, "Bold=1")
+	}
+	return strings.Join(fields, ",")
+}
+
+var hexColorRegex = regexp.MustCompile(`^(?:#|0x)?([0-9a-fA-F]{2})([0-9a-fA-F]{2})([0-9a-fA-F]{2})$`)
+
+// Convert a #RRGGBB (or 0xRRGGBB) color into the ASS &HBBGGRR format. Other formats, checked with IsColor, are kept as is
+func assColor(color string) string {
+	matches := hexColorRegex.FindStringSubmatch(color)
+	if matches == nil {
+		return color
+	}
+	return strings.ToUpper(fmt.Sprintf("&H%s%s%s", matches[3], matches[2], matches[1]))
+}
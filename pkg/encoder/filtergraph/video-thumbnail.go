@@ -0,0 +1,41 @@
+package filtergraph
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ThumbnailFilter Select the most representative frame among frames sampled at a regular interval
+// Documentation : https://ffmpeg.org/ffmpeg-filters.html#thumbnail
+type ThumbnailFilter struct {
+	Node
+	// Time between two sampled frames
+	interval time.Duration
+	// Number of sampled frames to choose from
+	frames int
+}
+
+func NewThumbnailFilter(target Filter, interval time.Duration, frames int) *ThumbnailFilter {
+	return &ThumbnailFilter{
+		Node{
+			name:     fmt.Sprintf("thumb_%s", uniqueSuffix()),
+			children: []Filter{target},
+		},
+		interval,
+		frames}
+}
+
+func (tf *ThumbnailFilter) Build() string {
+	// Expected format : [0:v]fps=fps=1/36,thumbnail=n=100[r1]
+	ss := strings.Builder{}
+	// First let the children Build themselves
+	ss.WriteString(tf.buildChildren())
+	ss.WriteString(fmt.Sprintf("[%s]fps=fps=1/%s,thumbnail=n=%d[%s];",
+		tf.children[0].Id(), formatFloat(tf.interval.Seconds()), tf.frames, tf.Id()))
+	return ss.String()
+}
+
+func (tf *ThumbnailFilter) Id() string {
+	return tf.label()
+}
@@ -0,0 +1,131 @@
+package filtergraph
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// AudioVisualizationFilter Render an animated visualization of the target audio over a background video.
+// As the audio is both visualized and kept as is, this filter has two outputs : Id() is the
+// resulting video, and Audio() is the untouched audio
+type AudioVisualizationFilter struct {
+	Node
+	params VisualizationParams
+}
+
+type VisualizationMode uint8
+
+const (
+	// Animated waveform
+	// Documentation : https://ffmpeg.org/ffmpeg-filters.html#showwaves
+	Waveform VisualizationMode = iota
+	// Scrolling spectrum
+	// Documentation : https://ffmpeg.org/ffmpeg-filters.html#showspectrum
+	Spectrum
+	// Frequency bars
+	// Documentation : https://ffmpeg.org/ffmpeg-filters.html#showfreqs
+	Frequencies
+)
+
+type VisualizationPosition uint8
+
+const (
+	// The visualization is centered on the background. This is the default
+	Center VisualizationPosition = iota
+	// The visualization is at the top of the background
+	Top
+	// The visualization is at the bottom of the background
+	Bottom
+)
+
+// VisualizationParams Appearance of the visualization
+type VisualizationParams struct {
+	Mode VisualizationMode
+	// Size of the visualization (px)
+	Width  int
+	Height int
+	// Color of the waveform or the bars. Not used by the spectrum
+	Color string
+	// Vertical position of the visualization on the background
+	Position VisualizationPosition
+}
+
+// Colors accepted by FFmpeg : #RRGGBB, 0xRRGGBB, with optional alpha, or a color name
+var colorRegex = regexp.MustCompile(`^((#|0x)?[0-9a-fA-F]{6}([0-9a-fA-F]{2})?|[a-zA-Z]+)(@[0-9.]+)?$`)
+
+// IsColor Return true if color can safely be used as an FFmpeg color
+func IsColor(color string) bool {
+	return colorRegex.MatchString(color)
+}
+
+// NewAudioVisualizationFilter Overlay a visualization of audio over background
+func NewAudioVisualizationFilter(audio Filter, background Filter, params VisualizationParams) *AudioVisualizationFilter {
+	if params.Width == 0 {
+		params.Width = 1280
+	}
+	if params.Height == 0 {
+		params.Height = 240
+	}
+	if params.Color == "" {
+		params.Color = "white"
+	}
+	return &AudioVisualizationFilter{
+		Node: Node{
+			name:     fmt.Sprintf("vis_%s", uniqueSuffix()),
+			children: []Filter{audio, background},
+		},
+		params: params,
+	}
+}
+
+func (avf *AudioVisualizationFilter) Build() string {
+	// Expected format : [a]asplit[out_a][vis_in];[vis_in]showwaves=s=1280x240:mode=cline:colors=white[wave];[0:v][wave]overlay=x=(W-w)/2:y=(H-h)/2:shortest=1[out_v]
+	ss := strings.Builder{}
+	// First let the children Build themselves
+	ss.WriteString(avf.buildChildren())
+	visIn := fmt.Sprintf("%s_in", avf.Id())
+	wave := fmt.Sprintf("%s_wave", avf.Id())
+	ss.WriteString(fmt.Sprintf("[%s]asplit[%s][%s];", avf.children[0].Id(), avf.Audio().Id(), visIn))
+	ss.WriteString(fmt.Sprintf("[%s]%s[%s];", visIn, avf.visualizer(), wave))
+	ss.WriteString(fmt.Sprintf("[%s][%s]overlay=x=(W-w)/2:y=%s:shortest=1[%s];", avf.children[1].Id(), wave, avf.y(), avf.Id()))
+	return ss.String()
+}
+
+// Visualization filter and its arguments
+func (avf *AudioVisualizationFilter) visualizer() string {
+	size := fmt.Sprintf("%dx%d", avf.params.Width, avf.params.Height)
+	switch avf.params.Mode {
+	case Spectrum:
+		return fmt.Sprintf("showspectrum=s=%s:mode=combined:slide=scroll:color=intensity", size)
+	case Frequencies:
+		return fmt.Sprintf("showfreqs=s=%s:mode=bar:colors=%s", size, avf.params.Color)
+	default:
+		return fmt.Sprintf("showwaves=s=%s:mode=cline:colors=%s", size, avf.params.Color)
+	}
+}
+
+// Vertical position of the visualization, as an overlay expression
+func (avf *AudioVisualizationFilter) y() string {
+	switch avf.params.Position {
+	case Top:
+		return "0"
+	case Bottom:
+		return "H-h"
+	default:
+		return "(H-h)/2"
+	}
+}
+
+func (avf *AudioVisualizationFilter) Id() string {
+	return avf.label()
+}
+
+// Audio The untouched audio output of the filter
+func (avf *AudioVisualizationFilter) Audio() Filter {
+	return newOutputFilter(avf, "a")
+}
+
+func (avf *AudioVisualizationFilter) secondaryOutputs() []string {
+	return []string{"a"}
+}
@@ -0,0 +1,69 @@
+package filtergraph
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// XfadeFilter Cross fade from a video to another one
+// /!\ Both videos must have the same resolution, framerate and pixel format /!\
+// Documentation : https://ffmpeg.org/ffmpeg-filters.html#xfade
+type XfadeFilter struct {
+	Node
+	// Name of the transition effect
+	transition string
+	// Duration of the transition
+	duration time.Duration
+	// Time of the first video at which the transition starts
+	offset time.Duration
+}
+
+// Transition effects supported by xfade. Custom expressions are not allowed
+var xfadeTransitions = []string{
+	"fade", "fadeblack", "fadewhite", "fadegrays", "dissolve", "pixelize", "radial", "distance",
+	"wipeleft", "wiperight", "wipeup", "wipedown", "slideleft", "slideright", "slideup", "slidedown",
+	"smoothleft", "smoothright", "smoothup", "smoothdown", "circlecrop", "rectcrop", "circleopen", "circleclose",
+	"vertopen", "vertclose", "horzopen", "horzclose", "diagtl", "diagtr", "diagbl", "diagbr",
+	"hlslice", "hrslice", "vuslice", "vdslice", "hblur", "squeezeh", "squeezev",
+}
+
+// IsTransition Return true if name is a transition effect supported by xfade
+func IsTransition(name string) bool {
+	for _, t := range xfadeTransitions {
+		if t == name {
+			return true
+		}
+	}
+	return false
+}
+
+func NewXfadeFilter(first Filter, second Filter, transition string, duration time.Duration, offset time.Duration) *XfadeFilter {
+	return &XfadeFilter{
+		Node{
+			name:     fmt.Sprintf("xfade_%s", uniqueSuffix()),
+			children: []Filter{first, second},
+		},
+		transition,
+		duration,
+		offset}
+}
+
+func (xf *XfadeFilter) Build() string {
+	// Expected format : [v1][v2]xfade=transition=fade:duration=1:offset=4.5[r1]
+	ss := strings.Builder{}
+	// First let the children Build themselves
+	ss.WriteString(xf.buildChildren())
+	ss.WriteString(fmt.Sprintf("[%s][%s]xfade=transition=%s:duration=%s:offset=%s[%s];",
+		xf.children[0].Id(),
+		xf.children[1].Id(),
+		xf.transition,
+		formatFloat(xf.duration.Seconds()),
+		formatFloat(xf.offset.Seconds()),
+		xf.Id()))
+	return ss.String()
+}
+
+func (xf *XfadeFilter) Id() string {
+	return xf.label()
+}
@@ -0,0 +1,240 @@
+package encoder
+
+import (
+	"encode-box/pkg/encoder/filtergraph"
+	"strings"
+	"time"
+)
+
+// PresetOptions Behaviour options shared by all presets.
+// A nil PresetOptions means that all defaults are used
+type PresetOptions struct {
+	// Normalization of the main audio track
+	Loudness LoudnessOptions
+	// How the main audio tracks are combined together
+	AudioLayout AudioLayout
+	// Processing chain of each main audio track, in the same order as the audio paths.
+	// A nil entry means the track is left untouched
+	Tracks []*TrackProcessing
+	// Parts of the main audio track to remove once all tracks are combined.
+	// See SilenceDetection.Cuts
+	Cuts []filtergraph.TimeRange
+	// Chapters of the output
+	Chapters []Chapter
+	// Subtitles of the output, either burnt or as a soft track. Nil if no subtitles
+	Subtitles *SubtitleOptions
+	// Animated visualization of the main audio track, drawn over the image or the background color.
+	// Nil to keep a static background. Not used when there is a video
+	Visualization *VisualizationOptions
+	// Texts drawn over the video, in drawing order
+	Texts []filtergraph.TextParams
+	// Paths of the clips played before and after the main content. Empty if none
+	Intro string
+	Outro string
+	// How the images are displayed, when there are multiple images
+	Slideshow SlideshowOptions
+	// Resolution and framerate of the output, all video inputs being scaled to fit in it.
+	// If zero, inputs keep their own size, unless clips are stitched around the main content or
+	// there is no video input at all, in which case filtergraph.DefaultCanvas is used
+	Canvas filtergraph.Canvas
+	// Codec and encoding speed/quality trade-off of the video track
+	Quality QualityOptions
+	// Encode a static image or background at the canvas framerate, instead of StillFrameRate
+	FullFrameRate bool
+	// Sample rate, sample format and channel layout of the output audio track.
+	// Unset fields default to filtergraph.DefaultAudioFormat
+	AudioFormat filtergraph.AudioFormat
+	// Arbitrary filters applied to the main audio track before its normalization, in order
+	AudioFilters []CustomFilter
+	// Arbitrary filters applied to the video track before drawing texts and subtitles, in order
+	VideoFilters []CustomFilter
+}
+
+// CustomFilter Any single input, single output FFmpeg filter, for needs not covered by the other options
+type CustomFilter struct {
+	// Name of the FFmpeg filter
+	Name string
+	// Options of the filter, in order
+	Options []filtergraph.RawOption
+}
+
+// Apply all filters on the target track, in order
+func applyCustomFilters(target filtergraph.Filter, filters []CustomFilter) filtergraph.Filter {
+	for _, f := range filters {
+		target = filtergraph.NewRawFilter(f.Name, f.Options, 1, target)
+	}
+	return target
+}
+
+// StillFrameRate Framerate of a video made of a static image or background (fps).
+// As every frame is the same, a higher framerate only slows down the encoding
+const StillFrameRate = 1
+
+// VisualizationOptions Visualization of the main audio track
+type VisualizationOptions struct {
+	filtergraph.VisualizationParams
+	// Color of the background when there is no image. Default to black
+	Background string
+}
+
+type AudioLayout uint8
+
+const (
+	// Audio tracks are parts of the same recording, played one after another. This is the default
+	ConcatLayout AudioLayout = iota
+	// Audio tracks are played simultaneously, each track being a different speaker
+	MixLayout
+)
+
+// TrackProcessing Filters applied to a single audio track, before it is combined with the others.
+// Filters are applied in the following order : highpass, lowpass, denoise, gate, compressor, gain
+type TrackProcessing struct {
+	// Cutoff frequency of the highpass filter (Hz). 0 to disable
+	Highpass int `json:"highpass"`
+	// Cutoff frequency of the lowpass filter (Hz). 0 to disable
+	Lowpass int `json:"lowpass"`
+	// Background noise reduction. Nil to disable
+	Denoise *filtergraph.DenoiseParams `json:"denoise,omitempty"`
+	// Noise gate. Nil to disable
+	Gate *filtergraph.GateParams `json:"gate,omitempty"`
+	// Dynamic range compression. Nil to disable
+	Compressor *filtergraph.CompressorParams `json:"compressor,omitempty"`
+	// Gain applied to the track (dB)
+	Gain float64 `json:"gain"`
+	// Relative volume of the track when using the mix layout, 0 muting it. Default to 1 if nil
+	Weight *float32 `json:"weight,omitempty"`
+}
+
+// SilenceOptions Detection and removal of silences in the main audio track
+type SilenceOptions struct {
+	// Level under which the audio is considered silent (dB). Default to -50
+	Threshold float64
+	// Minimum duration of a silence. Default to 2s
+	MinDuration time.Duration
+	// Remove the leading and trailing silences
+	TrimEdges bool
+	// Internal silences longer than this are shortened to this duration. 0 to disable
+	MaxSilence time.Duration
+}
+
+// LoudnessOptions How the main audio track loudness is normalized
+type LoudnessOptions struct {
+	Mode LoudnessMode
+	// EBU R128 targets, only used by loudnorm modes. Defaults to filtergraph.PodcastLoudness()
+	Targets filtergraph.LoudnormTargets
+}
+
+type LoudnessMode uint8
+
+const (
+	// Speechnorm normalization, fast and made for voices. This is the default
+	SpeechnormLoudness LoudnessMode = iota
+	// Single pass loudnorm. Dynamic, less precise
+	SinglePassLoudness
+	// Two pass loudnorm. The audio is first analysed, and the measurements are used
+	// to perform a linear normalization
+	TwoPassLoudness
+	// No normalization, the main audio track is kept as is. A single audio track already in
+	// the output format can then be copied without re-encoding it
+	NoLoudness
+)
+
+// Return opt, or the default options if opt is nil
+func withDefaults(opt *PresetOptions) *PresetOptions {
+	if opt == nil {
+		return &PresetOptions{}
+	}
+	return opt
+}
+
+// Return the loudnorm targets to use, falling back to the podcast profile
+func (lo *LoudnessOptions) targets() filtergraph.LoudnormTargets {
+	if lo.Targets == (filtergraph.LoudnormTargets{}) {
+		return filtergraph.PodcastLoudness()
+	}
+	return lo.Targets
+}
+
+// Return the processing chain of the i-th main audio track, if any
+func (o *PresetOptions) track(i int) *TrackProcessing {
+	if i >= len(o.Tracks) {
+		return nil
+	}
+	return o.Tracks[i]
+}
+
+// Return the weight of the i-th main audio track in the mix layout
+func (o *PresetOptions) weight(i int) float32 {
+	if tp := o.track(i); tp != nil && tp.Weight != nil {
+		return *tp.Weight
+	}
+	return 1
+}
+
+// Apply the processing chain on the target track
+func (tp *TrackProcessing) apply(target filtergraph.Filter) filtergraph.Filter {
+	if tp.Highpass > 0 {
+		target = filtergraph.NewAudioPassFilter(target, filtergraph.Highpass, tp.Highpass)
+	}
+	if tp.Lowpass > 0 {
+		target = filtergraph.NewAudioPassFilter(target, filtergraph.Lowpass, tp.Lowpass)
+	}
+	if tp.Denoise != nil {
+		target = filtergraph.NewAudioDenoiseFilter(target, *tp.Denoise)
+	}
+	if tp.Gate != nil {
+		target = filtergraph.NewAudioGateFilter(target, *tp.Gate)
+	}
+	if tp.Compressor != nil {
+		target = filtergraph.NewAudioCompressorFilter(target, *tp.Compressor)
+	}
+	if tp.Gain != 0 {
+		target = filtergraph.NewAudioGainFilter(target, tp.Gain)
+	}
+	return target
+}
+
+// Return the color of the background used when there is neither video nor image
+func (o *PresetOptions) background() string {
+	if o.Visualization != nil && o.Visualization.Background != "" {
+		return o.Visualization.Background
+	}
+	return "black"
+}
+
+// Return true if the video inputs must be scaled to the canvas
+func (o *PresetOptions) fitsCanvas() bool {
+	return o.Canvas != (filtergraph.Canvas{}) || o.hasClips()
+}
+
+// Return the canvas of the output
+func (o *PresetOptions) canvas() filtergraph.Canvas {
+	if o.Canvas == (filtergraph.Canvas{}) {
+		return filtergraph.DefaultCanvas
+	}
+	return o.Canvas
+}
+
+// Return true if a video made of a single image or a plain background does not change over time,
+// and can be encoded at StillFrameRate
+func (o *PresetOptions) isStill() bool {
+	// Custom filters may animate the video, there is no way to know
+	if o.FullFrameRate || o.Visualization != nil || o.hasClips() || (o.Subtitles != nil && o.Subtitles.Burn) || len(o.VideoFilters) > 0 {
+		return false
+	}
+	for _, text := range o.Texts {
+		if text.Start > 0 || text.End > 0 || strings.Contains(text.Text, filtergraph.TimestampPlaceholder) {
+			return false
+		}
+	}
+	return true
+}
+
+// Return the canvas of a video made of a single image or a plain background
+func (o *PresetOptions) stillCanvas() filtergraph.Canvas {
+	canvas := o.canvas()
+	if o.isStill() {
+		canvas.FrameRate = StillFrameRate
+	}
+	return canvas
+}
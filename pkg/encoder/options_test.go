@@ -0,0 +1,99 @@
+package encoder
+
+import (
+	"encode-box/pkg/encoder/filtergraph"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Tracks without explicit weights should be mixed at the same volume
+func TestPresetOptions_Weight(t *testing.T) {
+	half, mute := float32(0.5), float32(0)
+	opt := &PresetOptions{Tracks: []*TrackProcessing{nil, {Gain: 3}, {Weight: &half}, {Weight: &mute}}}
+	assert.Equal(t, float32(1), opt.weight(0))
+	assert.Equal(t, float32(1), opt.weight(1))
+	assert.Equal(t, float32(0.5), opt.weight(2))
+	// An explicit 0 mutes the track
+	assert.Equal(t, float32(0), opt.weight(3))
+	// Out of bounds
+	assert.Equal(t, float32(1), opt.weight(4))
+}
+
+func TestPresetOptions_AddAudioTracks_Mix(t *testing.T) {
+	builder := &Builder{}
+	builder.AddInput(&FileInput{Path: "video"})
+	weight := float32(2)
+	opt := &PresetOptions{AudioLayout: MixLayout, Tracks: []*TrackProcessing{{Weight: &weight}, nil}}
+	root := addAudioTracks(builder, []string{"a", "b"}, opt)
+	assert.Len(t, builder.inputs, 3)
+	assert.Equal(t, "[1][2]amix=weights=2 1["+root.Id()+"];", root.Build())
+}
+
+func TestPresetOptions_AddAudioTracks_Concat(t *testing.T) {
+	builder := &Builder{}
+	root := addAudioTracks(builder, []string{"a", "b", "c"}, withDefaults(nil))
+	assert.Len(t, builder.inputs, 3)
+	// Each track is normalized before being concatenated
+	assert.Contains(t, root.Build(), "[2]aresample=44100,aformat=sample_fmts=fltp:channel_layouts=stereo")
+	assert.Contains(t, root.Build(), "concat=n=3:v=0:a=1["+root.Id()+"];")
+}
+
+func TestPresetOptions_Background(t *testing.T) {
+	assert.Equal(t, "black", withDefaults(nil).background())
+	opt := &PresetOptions{Visualization: &VisualizationOptions{Background: "#102030"}}
+	assert.Equal(t, "#102030", opt.background())
+}
+
+func TestPresetOptions_Canvas(t *testing.T) {
+	opt := withDefaults(nil)
+	assert.False(t, opt.fitsCanvas())
+	assert.Equal(t, filtergraph.DefaultCanvas, opt.canvas())
+	// Clips must be normalized, so the default canvas is used
+	opt = &PresetOptions{Intro: "/tmp/intro.mp4"}
+	assert.True(t, opt.fitsCanvas())
+	vertical := filtergraph.Canvas{Width: 1080, Height: 1920, FrameRate: 30, Fit: filtergraph.BlurPad}
+	opt = &PresetOptions{Canvas: vertical}
+	assert.True(t, opt.fitsCanvas())
+	assert.Equal(t, vertical, opt.canvas())
+}
+
+func TestPresetOptions_Still(t *testing.T) {
+	opt := withDefaults(nil)
+	assert.True(t, opt.isStill())
+	assert.Equal(t, StillFrameRate, opt.stillCanvas().FrameRate)
+	// A static text doesn't change the video over time
+	opt = &PresetOptions{Texts: []filtergraph.TextParams{{Text: "Episode 1"}}}
+	assert.True(t, opt.isStill())
+	for _, opt := range []*PresetOptions{
+		{FullFrameRate: true},
+		{Visualization: &VisualizationOptions{}},
+		{Outro: "/tmp/outro.mp4"},
+		{Subtitles: &SubtitleOptions{Burn: true}},
+		{Texts: []filtergraph.TextParams{{Text: "Episode 1", End: 5 * time.Second}}},
+		{Texts: []filtergraph.TextParams{{Text: filtergraph.TimestampPlaceholder}}},
+		{VideoFilters: []CustomFilter{{Name: "hflip"}}},
+	} {
+		assert.False(t, opt.isStill(), "%+v", opt)
+		assert.Equal(t, filtergraph.DefaultCanvas, opt.stillCanvas())
+	}
+}
+
+func TestPresetOptions_CustomFilters(t *testing.T) {
+	opt := &PresetOptions{
+		AudioFilters: []CustomFilter{{Name: "aecho", Options: []filtergraph.RawOption{{Key: "in_gain", Value: "0.8"}}}},
+		VideoFilters: []CustomFilter{{Name: "hflip"}, {Name: "vflip"}},
+	}
+	builder := Builder{}
+	root, err := buildMainAudio(nil, &builder, []string{"/tmp/a.mp3"}, opt)
+	assert.NoError(t, err)
+	// Custom audio filters are applied before the normalization
+	assert.Regexp(t, `^\[0\]aecho=in_gain=\\'0.8\\'\[raw_\d+\];\[raw_\d+\]speechnorm`, root.Build())
+	// Custom filters may change the audio format
+	_, resampled := resampleMainAudio(root, []string{"/tmp/a.mp3"}, opt).(*filtergraph.AudioResampleFilter)
+	assert.True(t, resampled)
+
+	built := processVideo(filtergraph.NewInput("0:v"), opt).Build()
+	assert.Less(t, strings.Index(built, "hflip"), strings.Index(built, "vflip"))
+}
@@ -0,0 +1,118 @@
+package encoder
+
+import (
+	"encode-box/pkg/encoder/filtergraph"
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// VideoDecision How the video track of the output is produced from the source video
+type VideoDecision struct {
+	// True if the source video track is copied as is, without re-encoding
+	Copy bool `json:"copy"`
+	// Why the source video track is transcoded. Empty if copied
+	Reason string `json:"reason,omitempty"`
+}
+
+// Video codecs each output container can hold, by file extension
+var containerCodecs = map[string][]string{
+	".mp4":  {"h264", "hevc", "vp9", "av1"},
+	".m4v":  {"h264", "hevc"},
+	".mov":  {"h264", "hevc"},
+	".mkv":  {"h264", "hevc", "vp9", "av1"},
+	".webm": {"vp9", "av1"},
+}
+
+// Decide whether the video track of videoPath can be copied into output, or must be transcoded
+func decideVideoCopy(videoPath string, output string, opt *PresetOptions) VideoDecision {
+	// Processing the video requires decoding it anyway, no need to probe it
+	if reason := videoProcessing(opt); reason != "" {
+		return VideoDecision{Reason: reason}
+	}
+	info, err := Probe(videoPath)
+	if err != nil {
+		return VideoDecision{Reason: fmt.Sprintf("the source video could not be probed : %s", err)}
+	}
+	video := info.Video()
+	if video == nil {
+		return VideoDecision{Reason: "the source has no video stream"}
+	}
+	// A rotated video is displayed rotated only by players reading the display matrix, which is lost when copied
+	if video.Rotation != 0 {
+		return VideoDecision{Reason: fmt.Sprintf("the source video is rotated by %d degrees", video.Rotation)}
+	}
+	return canCopyVideo(video.Codec, output, opt)
+}
+
+// Return why the video track is modified by the options, or an empty string if it is left untouched
+func videoProcessing(opt *PresetOptions) string {
+	if opt.hasClips() {
+		return "clips are stitched around the video"
+	}
+	if len(opt.Cuts) > 0 {
+		return "silences are cut from the video"
+	}
+	if opt.fitsCanvas() {
+		return "the video is scaled to the canvas"
+	}
+	if len(opt.VideoFilters) > 0 {
+		return "custom filters are applied on the video"
+	}
+	if len(opt.Texts) > 0 {
+		return "texts are drawn over the video"
+	}
+	if opt.Subtitles != nil && opt.Subtitles.Burn {
+		return "subtitles are burnt into the video"
+	}
+	return ""
+}
+
+// Audio codecs each output container can hold as is, by file extension. Only the codec encoded
+// by default (AAC) is copied, other ones would change the output audio codec
+var containerAudioCodecs = map[string][]string{
+	".mp4": {"aac"},
+	".m4v": {"aac"},
+	".mov": {"aac"},
+	".mkv": {"aac"},
+}
+
+// Return the stream to map as the output audio, and whether it can be copied. A main audio track left untouched
+// by the filtergraph is read from its input, and copied as is when it already meets the output format
+func mainAudioStream(audioRoot filtergraph.Filter, audioPaths []string, output string, opt *PresetOptions) (filtergraph.Filter, bool) {
+	input, untouched := audioRoot.(*filtergraph.InputFilter)
+	if !untouched || len(audioPaths) != 1 {
+		return audioRoot, false
+	}
+	// Only the audio stream is used, the input may also hold a cover
+	stream := filtergraph.NewInput(fmt.Sprintf("%s:a", input.Id()))
+	if !allAudiosMatch(audioPaths, opt.AudioFormat.WithDefaults()) {
+		return stream, false
+	}
+	info, err := Probe(audioPaths[0])
+	if err != nil {
+		return stream, false
+	}
+	ext := strings.ToLower(filepath.Ext(output))
+	for _, c := range containerAudioCodecs[ext] {
+		if c == info.Audio().Codec {
+			return stream, true
+		}
+	}
+	return stream, false
+}
+
+// Decide whether a source video track of the given codec can be copied into output
+func canCopyVideo(codec string, output string, opt *PresetOptions) VideoDecision {
+	requested := opt.Quality.Codec.probeName()
+	if codec != requested {
+		return VideoDecision{Reason: fmt.Sprintf("the source codec %s is not the requested codec %s", codec, requested)}
+	}
+	ext := strings.ToLower(filepath.Ext(output))
+	for _, c := range containerCodecs[ext] {
+		if c == codec {
+			return VideoDecision{Copy: true}
+		}
+	}
+	return VideoDecision{Reason: fmt.Sprintf("the source codec %s can't be stored in a %s output", codec, ext)}
+}
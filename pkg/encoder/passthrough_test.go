@@ -0,0 +1,50 @@
+package encoder
+
+import (
+	"encode-box/pkg/encoder/filtergraph"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestPassthrough_VideoProcessing(t *testing.T) {
+	assert.Empty(t, videoProcessing(withDefaults(nil)))
+	for _, opt := range []*PresetOptions{
+		{Intro: "/tmp/intro.mp4"},
+		{Canvas: filtergraph.DefaultCanvas},
+		{Texts: []filtergraph.TextParams{{Text: "Episode 1"}}},
+		{Subtitles: &SubtitleOptions{Burn: true}},
+		{VideoFilters: []CustomFilter{{Name: "hflip"}}},
+		{Cuts: []filtergraph.TimeRange{{Start: 0, End: time.Second}}},
+	} {
+		assert.NotEmpty(t, videoProcessing(opt), "%+v", opt)
+	}
+	// Soft subtitles are a separate track
+	assert.Empty(t, videoProcessing(&PresetOptions{Subtitles: &SubtitleOptions{}}))
+}
+
+func TestPassthrough_CanCopyVideo(t *testing.T) {
+	assert.Equal(t, VideoDecision{Copy: true}, canCopyVideo("h264", "/tmp/out.mp4", withDefaults(nil)))
+	assert.True(t, canCopyVideo("hevc", "/tmp/out.MKV", &PresetOptions{Quality: QualityOptions{Codec: H265}}).Copy)
+	// The requested codec must be honored
+	decision := canCopyVideo("h264", "/tmp/out.mp4", &PresetOptions{Quality: QualityOptions{Codec: VP9}})
+	assert.False(t, decision.Copy)
+	assert.Equal(t, "the source codec h264 is not the requested codec vp9", decision.Reason)
+	// And the container must be able to hold the codec
+	decision = canCopyVideo("vp9", "/tmp/out.mov", &PresetOptions{Quality: QualityOptions{Codec: VP9}})
+	assert.False(t, decision.Copy)
+	assert.Equal(t, "the source codec vp9 can't be stored in a .mov output", decision.Reason)
+}
+
+func TestPassthrough_MainAudioStream(t *testing.T) {
+	opt := withDefaults(nil)
+	// A processed audio track is mapped from the filtergraph
+	norm := filtergraph.NewAudioNormalizationFilter(filtergraph.NewInput("1"), filtergraph.Speechnorm)
+	stream, copied := mainAudioStream(norm, []string{"/tmp/a.m4a"}, "/tmp/out.mp4", opt)
+	assert.Equal(t, norm, stream)
+	assert.False(t, copied)
+	// An untouched one is read from its input, only copied once probed
+	stream, copied = mainAudioStream(filtergraph.NewInput("1"), []string{"/tmp/a.m4a"}, "/tmp/out.mp4", opt)
+	assert.Equal(t, "1:a", stream.Id())
+	assert.False(t, copied)
+}
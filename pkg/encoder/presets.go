@@ -4,139 +4,259 @@ import (
 	"context"
 	"encode-box/pkg/encoder/filtergraph"
 	"fmt"
+	"strconv"
 )
 
 // GetAudiosVideoEnc Return an initialized encoder with a video and one/multiple audio
 // If multiple audios are specified, they will be concatenated
 // The resulting video will have the normalized audio overlaid over the video audio track
-func GetAudiosVideoEnc(ctx *context.Context, videoPath string, audioPaths []string, output string) (*Encoder, error) {
+func GetAudiosVideoEnc(ctx *context.Context, videoPath string, audioPaths []string, output string, opt *PresetOptions) (*Encoder, error) {
+	opt, err := withCutSubtitles(withDefaults(opt), output)
+	if err != nil {
+		return nil, err
+	}
 	builder := Builder{}
 	// video track
 	builder.AddInput(&FileInput{Path: videoPath})
-	videoTrack := filtergraph.NewInput("0")
+	// Remove the silences cut from the main audio track, so that the video stays in sync with it
+	var videoRoot filtergraph.Filter = filtergraph.NewInput("0:v")
+	if len(opt.Cuts) > 0 {
+		videoRoot = filtergraph.NewVideoCutFilter(videoRoot, opt.Cuts)
+	}
+	// Scale the video to the canvas and apply video processing, if any
+	videoRoot = processVideo(fitCanvas(videoRoot, opt), opt)
 
-	var graphRoot filtergraph.Filter
+	// audio tracks, concatenated and normalized
+	graphRoot, err := buildMainAudio(ctx, &builder, audioPaths, opt)
+	if err != nil {
+		return nil, err
+	}
 
-	// audio tracks
-	if len(audioPaths) == 1 {
-		// Only one audio track, NO-OP
-		builder.AddInput(&FileInput{Path: audioPaths[0]})
-		graphRoot = filtergraph.NewInput("1")
-	} else {
-		// If multiple tracks are specified, concat them
-		var aFilterInput []filtergraph.Filter
-		for i, aPath := range audioPaths {
-			builder.AddInput(&FileInput{Path: aPath})
-			// Add a new audio track input indexed by one (0 being the video track)
-			aTrack := filtergraph.NewInput(fmt.Sprintf("%d", i+1))
-			aFilterInput = append(aFilterInput, aTrack)
+	// Then, mix the video audio track with the combined audio track. A silent video, such as
+	// a screen capture, has nothing to mix : the combined audio track is used as is
+	mixedPaths := audioPaths
+	if hasAudioStream(videoPath) {
+		var videoTrack filtergraph.Filter = filtergraph.NewInput("0:a")
+		if len(opt.Cuts) > 0 {
+			videoTrack = filtergraph.NewAudioCutFilter(videoTrack, opt.Cuts)
 		}
-		graphRoot = filtergraph.NewAudioConcatFilter(aFilterInput...)
-
+		graphRoot = filtergraph.NewAudioMixFilter(videoTrack, graphRoot, filtergraph.WithoutModulation, [2]float32{1, 0.2})
+		mixedPaths = append(append([]string{}, audioPaths...), videoPath)
 	}
-	// In any way, normalize...
-	graphRoot = filtergraph.NewAudioNormalizationFilter(graphRoot, filtergraph.Speechnorm)
 
-	// ... and resample the resulting audio
-	graphRoot = filtergraph.NewAudioResampleFilter(graphRoot, filtergraph.K44)
+	// ... and resample the resulting audio. The mix negotiates its own format, the video audio track must also be checked
+	graphRoot = resampleMainAudio(graphRoot, mixedPaths, opt)
+
+	// Stitch the intro and outro clips, if any
+	graphRoot, videoRoot, offset, err := addIntroOutro(&builder, graphRoot, videoRoot, opt, nil)
+	if err != nil {
+		return nil, err
+	}
 
-	// Finally, mix the video track with the combined audio track
-	graphRoot = filtergraph.NewAudioMixFilter(videoTrack, graphRoot, filtergraph.WithoutModulation, [2]float32{1, 0.2})
+	// And assign the graph to the command. A main audio track left untouched and already in the output format is copied
+	audioRoot, copyAudio := mainAudioStream(graphRoot, audioPaths, output, opt)
+	builder.SetFilterGraph(audioRoot).AddFilterGraph(videoRoot)
+	if copyAudio {
+		builder.AddOutputOption("-c:a", "copy")
+	}
 
-	// And assign the graph to the command
-	builder.SetFilterGraph(graphRoot)
+	// Copy the source video track when possible, as re-encoding it is by far the slowest part of the job
+	decision := decideVideoCopy(videoPath, output, opt)
+	if decision.Copy {
+		builder.AddOutputOption("-c:v", "copy")
+	} else {
+		addQualityOptions(&builder, opt.Quality, false)
+	}
 
 	// Map the output -> Take the video from the only video source and the audio from the normalized audio track
-	builder.AddOutputOption("-map 0:v").AddOutputOption(fmt.Sprintf("-map [%s]", graphRoot.Id()))
+	builder.MapStream(videoRoot).MapStream(audioRoot)
 
 	// Set the output of the encoder
 	builder.SetOutput(output)
 
-	return builder.Build(ctx)
+	// Add soft subtitles and navigable chapters, if any
+	addSoftSubtitles(&builder, opt.Subtitles, offset)
+	if err := addChapters(&builder, delayChapters(opt.Chapters, offset)); err != nil {
+		return nil, err
+	}
+
+	enc, err := builder.Build(ctx)
+	if err != nil {
+		return nil, err
+	}
+	enc.Video = &decision
+	return enc, nil
 }
 
 // GetAudiosVideoEnc Return an initialized encoder with a video and one/multiple audio
 // If multiple audios are specified, they will be concatenated
 // The resulting video will have the normalized audio overlaid over the video audio track
-func GetAudiosImageEnc(ctx *context.Context, imagePath string, audioPaths []string, output string) (*Encoder, error) {
+func GetAudiosImageEnc(ctx *context.Context, imagePath string, audioPaths []string, output string, opt *PresetOptions) (*Encoder, error) {
+	opt, err := withCutSubtitles(withDefaults(opt), output)
+	if err != nil {
+		return nil, err
+	}
 	builder := Builder{}
-	// video track
-	builder.AddInput(&FileInput{Path: imagePath, Options: []string{"-loop 1"}})
-	var graphRoot filtergraph.Filter
+	// video track. A still image is looped at a low framerate
+	imageOptions := []string{"-loop", "1"}
+	if opt.isStill() {
+		imageOptions = append(imageOptions, "-framerate", strconv.Itoa(StillFrameRate))
+	}
+	builder.AddInput(&FileInput{Path: imagePath, Options: imageOptions})
+	// audio tracks, concatenated and normalized
+	graphRoot, err := buildMainAudio(ctx, &builder, audioPaths, opt)
+	if err != nil {
+		return nil, err
+	}
 
-	// audio tracks
-	if len(audioPaths) == 1 {
-		// Only one audio track, NO-OP
-		builder.AddInput(&FileInput{Path: audioPaths[0]})
-		graphRoot = filtergraph.NewInput("1")
-	} else {
-		// If multiple tracks are specified, concat them
-		var aFilterInput []filtergraph.Filter
-		for i, aPath := range audioPaths {
-			builder.AddInput(&FileInput{Path: aPath})
-			// Add a new audio track input indexed by one (0 being the video track)
-			aTrack := filtergraph.NewInput(fmt.Sprintf("%d", i+1))
-			aFilterInput = append(aFilterInput, aTrack)
-		}
-		graphRoot = filtergraph.NewAudioConcatFilter(aFilterInput...)
+	// ... and resample the resulting audio
+	graphRoot = resampleMainAudio(graphRoot, audioPaths, opt)
 
+	// Scale the image to the canvas. Without canvas, libx264 still requires even dimensions
+	var videoRoot filtergraph.Filter = filtergraph.NewInput("0:v")
+	if opt.fitsCanvas() {
+		videoRoot = filtergraph.NewVideoNormalizeFilter(videoRoot, opt.stillCanvas())
+	} else {
+		videoRoot = filtergraph.NewVideoEvenSizeFilter(videoRoot)
 	}
-	// In any way, normalize...
-	graphRoot = filtergraph.NewAudioNormalizationFilter(graphRoot, filtergraph.Speechnorm)
 
-	// ... and resample the resulting audio
-	graphRoot = filtergraph.NewAudioResampleFilter(graphRoot, filtergraph.K44)
+	// Draw the audio visualization over the image, if any, then apply video processing
+	graphRoot, videoRoot = addVisualization(graphRoot, videoRoot, opt)
+	videoRoot = processVideo(videoRoot, opt)
 
-	// And assign the graph to the command
-	builder.SetFilterGraph(graphRoot)
+	// Stitch the intro and outro clips, if any. The looped video must end with the audio
+	graphRoot, videoRoot, offset, err := addIntroOutro(&builder, graphRoot, videoRoot, opt, audioPaths)
+	if err != nil {
+		return nil, err
+	}
+
+	// And assign the graph to the command. A main audio track left untouched and already in the output format is copied
+	audioRoot, copyAudio := mainAudioStream(graphRoot, audioPaths, output, opt)
+	builder.SetFilterGraph(audioRoot).AddFilterGraph(videoRoot)
+	if copyAudio {
+		builder.AddOutputOption("-c:a", "copy")
+	}
 
 	// Add general options
 	builder.
 		// Set the pixel space
-		AddOutputOption("-pix_fmt yuv420p").
-		// Set the codec to be used
-		AddOutputOption("-c:v libx264").
-		// As we are using a static image, we can configure x264 to optimize for it
-		AddOutputOption("-tune stillimage").
+		AddOutputOption("-pix_fmt", "yuv420p").
 		// And end the video at the shortest input (the audio)
 		AddOutputOption("-shortest")
+	// Set the codec to be used. Without visualization nor clips, the video is a static image, the encoder can optimize for it
+	addQualityOptions(&builder, opt.Quality, opt.Visualization == nil && !opt.hasClips())
 	// Map the output -> Take the video from the only video source and the audio from the normalized audio track
-	builder.AddOutputOption("-map 0:v").AddOutputOption(fmt.Sprintf("-map [%s]", graphRoot.Id()))
+	builder.MapStream(videoRoot).MapStream(audioRoot)
 
 	// Set the output of the encoder
 	builder.SetOutput(output)
 
+	// Add soft subtitles and navigable chapters, if any
+	addSoftSubtitles(&builder, opt.Subtitles, offset)
+	if err := addChapters(&builder, delayChapters(opt.Chapters, offset)); err != nil {
+		return nil, err
+	}
+
 	return builder.Build(ctx)
 }
 
-// GetAudiosOnlyEnc Return an initialized encoder with a black background and multiple audio tracks
+// GetAudiosSlideshowEnc Return an initialized encoder with multiple images and one/multiple audio
 // If multiple audios are specified, they will be concatenated
-// The resulting video will have a black background over the video audio track
-func GetAudiosOnlyEnc(ctx *context.Context, audioPaths []string, sideAudioPath string, output string) (*Encoder, error) {
+// The images are displayed one after another over the audio, according to opt.Slideshow
+func GetAudiosSlideshowEnc(ctx *context.Context, imagePaths []string, audioPaths []string, output string, opt *PresetOptions) (*Encoder, error) {
+	opt, err := withCutSubtitles(withDefaults(opt), output)
+	if err != nil {
+		return nil, err
+	}
+	if len(imagePaths) == 0 {
+		return nil, fmt.Errorf("a slideshow requires at least one image")
+	}
+	if opt.Slideshow.Transition != "" && !filtergraph.IsTransition(opt.Slideshow.Transition) {
+		return nil, fmt.Errorf("unknown transition \"%s\"", opt.Slideshow.Transition)
+	}
 	builder := Builder{}
-	// video track
-	builder.AddInput(&FileInput{Path: "color=black:s=1280x720:r=25", Format: "lavfi"})
-	var graphRoot filtergraph.Filter
+	// The slideshow must last as long as the audio
+	total, err := mainAudioDuration(audioPaths, opt)
+	if err != nil {
+		return nil, fmt.Errorf("could not get main audio duration : %w", err)
+	}
+	durations, err := slideDurations(len(imagePaths), total, &opt.Slideshow)
+	if err != nil {
+		return nil, err
+	}
+	// video tracks, all fitting in the canvas
+	videoRoot := addSlideshow(&builder, imagePaths, durations, opt)
 
-	// audio tracks
-	if len(audioPaths) == 1 {
-		// Only one audio track, NO-OP
-		builder.AddInput(&FileInput{Path: audioPaths[0]})
-		graphRoot = filtergraph.NewInput("1")
-	} else {
-		// If multiple tracks are specified, concat them
-		var aFilterInput []filtergraph.Filter
-		for i, aPath := range audioPaths {
-			builder.AddInput(&FileInput{Path: aPath})
-			// Add a new audio track input indexed by one (0 being the video track)
-			aTrack := filtergraph.NewInput(fmt.Sprintf("%d", i+1))
-			aFilterInput = append(aFilterInput, aTrack)
-		}
-		graphRoot = filtergraph.NewAudioConcatFilter(aFilterInput...)
+	// audio tracks, concatenated and normalized
+	graphRoot, err := buildMainAudio(ctx, &builder, audioPaths, opt)
+	if err != nil {
+		return nil, err
+	}
+
+	// ... and resample the resulting audio
+	graphRoot = resampleMainAudio(graphRoot, audioPaths, opt)
+
+	// Draw the audio visualization over the slideshow, if any, then apply video processing
+	graphRoot, videoRoot = addVisualization(graphRoot, videoRoot, opt)
+	videoRoot = processVideo(videoRoot, opt)
+
+	// Stitch the intro and outro clips, if any. The slideshow already ends with the audio
+	graphRoot, videoRoot, offset, err := addIntroOutro(&builder, graphRoot, videoRoot, opt, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// And assign the graph to the command. A main audio track left untouched and already in the output format is copied
+	audioRoot, copyAudio := mainAudioStream(graphRoot, audioPaths, output, opt)
+	builder.SetFilterGraph(audioRoot).AddFilterGraph(videoRoot)
+	if copyAudio {
+		builder.AddOutputOption("-c:a", "copy")
+	}
+
+	// Add general options
+	builder.
+		// Set the pixel space
+		AddOutputOption("-pix_fmt", "yuv420p").
+		// And end the video at the shortest input (the audio)
+		AddOutputOption("-shortest")
+	// Set the codec to be used
+	addQualityOptions(&builder, opt.Quality, false)
+	// Map the output -> Take the video from the slideshow and the audio from the normalized audio track
+	builder.MapStream(videoRoot).MapStream(audioRoot)
+
+	// Set the output of the encoder
+	builder.SetOutput(output)
+
+	// Add soft subtitles and navigable chapters, if any
+	addSoftSubtitles(&builder, opt.Subtitles, offset)
+	if err := addChapters(&builder, delayChapters(opt.Chapters, offset)); err != nil {
+		return nil, err
+	}
 
+	return builder.Build(ctx)
+}
+
+// GetAudiosOnlyEnc Return an initialized encoder with a colored background and multiple audio tracks
+// If multiple audios are specified, they will be concatenated
+// The resulting video will have a black background (or the visualization one) over the video audio track
+func GetAudiosOnlyEnc(ctx *context.Context, audioPaths []string, sideAudioPath string, output string, opt *PresetOptions) (*Encoder, error) {
+	opt, err := withCutSubtitles(withDefaults(opt), output)
+	if err != nil {
+		return nil, err
+	}
+	builder := Builder{}
+	// video track
+	// The background is directly generated with the canvas size, at a low framerate if nothing is drawn over it
+	canvas := opt.stillCanvas()
+	builder.AddInput(&FileInput{
+		Path:   fmt.Sprintf("color=%s:s=%dx%d:r=%d", opt.background(), canvas.Width, canvas.Height, canvas.FrameRate),
+		Format: "lavfi",
+	})
+	// audio tracks, concatenated and normalized
+	graphRoot, err := buildMainAudio(ctx, &builder, audioPaths, opt)
+	if err != nil {
+		return nil, err
 	}
-	// In any way, normalize...
-	graphRoot = filtergraph.NewAudioNormalizationFilter(graphRoot, filtergraph.Speechnorm)
 
 	// If a side audio track is specified, add it to the mix
 	if sideAudioPath != "" {
@@ -151,24 +271,187 @@ func GetAudiosOnlyEnc(ctx *context.Context, audioPaths []string, sideAudioPath s
 		graphRoot = filtergraph.NewAudioMixFilter(graphRoot, sideTrack, filtergraph.WithoutModulation, [2]float32{1, 0.85})
 	}
 
-	// And assign the graph to the command
-	builder.SetFilterGraph(graphRoot)
+	// ... and resample the resulting audio. The side audio track is part of the mix, and must also be checked
+	mixedPaths := audioPaths
+	if sideAudioPath != "" {
+		mixedPaths = append(append([]string{}, audioPaths...), sideAudioPath)
+	}
+	graphRoot = resampleMainAudio(graphRoot, mixedPaths, opt)
+
+	// Draw the audio visualization over the background, if any, then apply video processing
+	graphRoot, videoRoot := addVisualization(graphRoot, filtergraph.NewInput("0:v"), opt)
+	videoRoot = processVideo(videoRoot, opt)
+
+	// Stitch the intro and outro clips, if any. The looped video must end with the audio
+	graphRoot, videoRoot, offset, err := addIntroOutro(&builder, graphRoot, videoRoot, opt, audioPaths)
+	if err != nil {
+		return nil, err
+	}
+
+	// And assign the graph to the command. A main audio track left untouched and already in the output format is copied
+	audioRoot, copyAudio := mainAudioStream(graphRoot, audioPaths, output, opt)
+	builder.SetFilterGraph(audioRoot).AddFilterGraph(videoRoot)
+	if copyAudio {
+		builder.AddOutputOption("-c:a", "copy")
+	}
 
 	// Add general options
 	builder.
 		// Set the pixel space
-		AddOutputOption("-pix_fmt yuv420p").
-		// Set the codec to be used
-		AddOutputOption("-c:v libx264").
-		// As we are using a static image, we can configure x264 to optimize for it
-		AddOutputOption("-tune stillimage").
+		AddOutputOption("-pix_fmt", "yuv420p").
 		// And end the video at the shortest input (the audio)
 		AddOutputOption("-shortest")
+	// Set the codec to be used. Without visualization nor clips, the video is a static image, the encoder can optimize for it
+	addQualityOptions(&builder, opt.Quality, opt.Visualization == nil && !opt.hasClips())
 	// Map the output -> Take the video from the only video source and the audio from the normalized audio track
-	builder.AddOutputOption("-map 0:v").AddOutputOption(fmt.Sprintf("-map [%s]", graphRoot.Id()))
+	builder.MapStream(videoRoot).MapStream(audioRoot)
 
 	// Set the output of the encoder
 	builder.SetOutput(output)
 
+	// Add soft subtitles and navigable chapters, if any
+	addSoftSubtitles(&builder, opt.Subtitles, offset)
+	if err := addChapters(&builder, delayChapters(opt.Chapters, offset)); err != nil {
+		return nil, err
+	}
+
 	return builder.Build(ctx)
 }
+
+// Add all audioPaths as inputs of the builder, process each of them, and combine them
+// according to the audio layout. Return the resulting audio track, without the parts in opt.Cuts
+func addAudioTracks(builder *Builder, audioPaths []string, opt *PresetOptions) filtergraph.Filter {
+	// Inputs are indexed by their position in the command
+	firstIndex := len(builder.inputs)
+	var aFilterInput []filtergraph.Filter
+	for i, aPath := range audioPaths {
+		builder.AddInput(&FileInput{Path: aPath})
+		var aTrack filtergraph.Filter = filtergraph.NewInput(fmt.Sprintf("%d", firstIndex+i))
+		if tp := opt.track(i); tp != nil {
+			aTrack = tp.apply(aTrack)
+		}
+		aFilterInput = append(aFilterInput, aTrack)
+	}
+
+	var graphRoot filtergraph.Filter
+	if len(aFilterInput) == 1 {
+		// Only one audio track, NO-OP
+		graphRoot = aFilterInput[0]
+	} else if opt.AudioLayout == MixLayout {
+		// Each track is a speaker, they must all be heard at the same time.
+		// The whole mix will be normalized afterwards, so weights are only relative volumes
+		weights := make([]float32, len(aFilterInput))
+		for i := range weights {
+			weights[i] = opt.weight(i)
+		}
+		graphRoot = filtergraph.NewAudioMultiMixFilter(aFilterInput, weights)
+	} else {
+		// If multiple tracks are specified, concat them
+		graphRoot = filtergraph.NewAudioConcatFilter(opt.AudioFormat, aFilterInput...)
+	}
+
+	if len(opt.Cuts) > 0 {
+		graphRoot = filtergraph.NewAudioCutFilter(graphRoot, opt.Cuts)
+	}
+	return graphRoot
+}
+
+// Add all audioPaths as inputs of the builder, and return the main audio track
+// processed, combined and normalized according to opt
+func buildMainAudio(ctx *context.Context, builder *Builder, audioPaths []string, opt *PresetOptions) (filtergraph.Filter, error) {
+	graphRoot := applyCustomFilters(addAudioTracks(builder, audioPaths, opt), opt.AudioFilters)
+	switch opt.Loudness.Mode {
+	case SinglePassLoudness:
+		return filtergraph.NewLoudnormFilter(graphRoot, opt.Loudness.targets(), nil), nil
+	case NoLoudness:
+		return graphRoot, nil
+	case TwoPassLoudness:
+		// The first pass analyses the audio tracks, the second one is the real encoding
+		measured, err := MeasureLoudness(ctx, audioPaths, opt)
+		if err != nil {
+			return nil, fmt.Errorf("could not measure audio loudness : %w", err)
+		}
+		return filtergraph.NewLoudnormFilter(graphRoot, opt.Loudness.targets(), measured), nil
+	default:
+		return filtergraph.NewAudioNormalizationFilter(graphRoot, filtergraph.Speechnorm), nil
+	}
+}
+
+// Return false if the media at path has no audio stream. A media that can't be probed is assumed to have one,
+// FFmpeg will report the error if it doesn't
+func hasAudioStream(path string) bool {
+	info, err := Probe(path)
+	if err != nil {
+		return true
+	}
+	return info.HasAudio()
+}
+
+// Resample the main audio track to the output audio format, unless all tracks already share its sample rate,
+// sample format and channels count. Loudnorm always outputs a 192kHz audio, and custom filters may change the format,
+// both must be resampled
+func resampleMainAudio(graphRoot filtergraph.Filter, audioPaths []string, opt *PresetOptions) filtergraph.Filter {
+	format := opt.AudioFormat.WithDefaults()
+	keepsFormat := opt.Loudness.Mode == SpeechnormLoudness || opt.Loudness.Mode == NoLoudness
+	if keepsFormat && len(opt.AudioFilters) == 0 && allAudiosMatch(audioPaths, format) {
+		return graphRoot
+	}
+	return filtergraph.NewAudioResampleFilter(graphRoot, format)
+}
+
+// Return true if the first audio stream of each path has the sample rate, sample format and channels count of the given format.
+// Any media that can't be probed is considered as not matching
+func allAudiosMatch(audioPaths []string, format filtergraph.AudioFormat) bool {
+	sampleRate, _ := strconv.Atoi(string(format.SampleRate))
+	for _, aPath := range audioPaths {
+		info, err := Probe(aPath)
+		if err != nil {
+			return false
+		}
+		audio := info.Audio()
+		if audio == nil || audio.SampleRate != sampleRate || audio.SampleFormat != string(format.SampleFormat) || audio.Channels != format.ChannelLayout.Channels() {
+			return false
+		}
+	}
+	return true
+}
+
+// Draw the visualization of the audio track over the video track, if requested.
+// Return the resulting audio and video tracks
+func addVisualization(audioRoot filtergraph.Filter, videoRoot filtergraph.Filter, opt *PresetOptions) (filtergraph.Filter, filtergraph.Filter) {
+	if opt.Visualization == nil {
+		return audioRoot, videoRoot
+	}
+	params := opt.Visualization.VisualizationParams
+	// By default, the visualization is as wide as the canvas
+	if params.Width == 0 && opt.fitsCanvas() {
+		params.Width = opt.canvas().Width
+	}
+	vis := filtergraph.NewAudioVisualizationFilter(audioRoot, videoRoot, params)
+	return vis.Audio(), vis
+}
+
+// Scale the video track to the canvas, if required
+func fitCanvas(videoRoot filtergraph.Filter, opt *PresetOptions) filtergraph.Filter {
+	if !opt.fitsCanvas() {
+		return videoRoot
+	}
+	return filtergraph.NewVideoNormalizeFilter(videoRoot, opt.canvas())
+}
+
+// Apply all video processing options on the video track
+func processVideo(videoRoot filtergraph.Filter, opt *PresetOptions) filtergraph.Filter {
+	videoRoot = applyCustomFilters(videoRoot, opt.VideoFilters)
+	for _, text := range opt.Texts {
+		videoRoot = filtergraph.NewDrawTextFilter(videoRoot, text)
+	}
+	if opt.Subtitles != nil && opt.Subtitles.Burn {
+		videoRoot = filtergraph.NewSubtitlesFilter(videoRoot, opt.Subtitles.Path, opt.Subtitles.Style)
+	}
+	return videoRoot
+}
+
+// Add the output options encoding the video track according to the quality options
+func addQualityOptions(builder *Builder, quality QualityOptions, stillImage bool) {
+	builder.AddOutputOption(quality.outputOptions(stillImage)...)
+}
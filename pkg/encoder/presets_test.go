@@ -2,11 +2,15 @@ package encoder
 
 import (
 	"context"
+	"encode-box/pkg/encoder/filtergraph"
 	"fmt"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"os"
 	"path"
+	"strings"
 	"testing"
+	"time"
 )
 
 const (
@@ -38,7 +42,7 @@ func TestEncodeBox_GetAudiosVideo_SingleAudio(t *testing.T) {
 	dir, out := Setup(t)
 	defer Teardown(t, dir)
 	ctx := context.Background()
-	enc, err := GetAudiosVideoEnc(&ctx, TestVideo, []string{TestAudio1}, out)
+	enc, err := GetAudiosVideoEnc(&ctx, TestVideo, []string{TestAudio1}, out, nil)
 	assert.Nil(t, err)
 	err = runEncoding(t, enc)
 	assert.Nil(t, err)
@@ -49,7 +53,7 @@ func TestEncodeBox_GetAudiosVideo_MultipleAudios(t *testing.T) {
 	dir, out := Setup(t)
 	defer Teardown(t, dir)
 	ctx := context.Background()
-	enc, err := GetAudiosVideoEnc(&ctx, TestVideo, []string{TestAudio1, TestAudio2, TestAudio3}, out)
+	enc, err := GetAudiosVideoEnc(&ctx, TestVideo, []string{TestAudio1, TestAudio2, TestAudio3}, out, nil)
 	err = runEncoding(t, enc)
 	assert.Nil(t, err)
 }
@@ -59,7 +63,7 @@ func TestEncodeBox_getAudiosImage_SingleAudio(t *testing.T) {
 	dir, out := Setup(t)
 	defer Teardown(t, dir)
 	ctx := context.Background()
-	enc, err := GetAudiosImageEnc(&ctx, TestImage, []string{TestAudio1}, out)
+	enc, err := GetAudiosImageEnc(&ctx, TestImage, []string{TestAudio1}, out, nil)
 	err = runEncoding(t, enc)
 	assert.Nil(t, err)
 }
@@ -69,7 +73,7 @@ func TestEncodeBox_getAudiosImage_MultipleAudios(t *testing.T) {
 	dir, out := Setup(t)
 	defer Teardown(t, dir)
 	ctx := context.Background()
-	enc, err := GetAudiosImageEnc(&ctx, TestImage, []string{TestAudio1, TestAudio2, TestAudio3}, out)
+	enc, err := GetAudiosImageEnc(&ctx, TestImage, []string{TestAudio1, TestAudio2, TestAudio3}, out, nil)
 	err = runEncoding(t, enc)
 	assert.Nil(t, err)
 }
@@ -79,7 +83,7 @@ func TestEncodeBox_getAudiosOnly_SingleAudio(t *testing.T) {
 	dir, out := Setup(t)
 	defer Teardown(t, dir)
 	ctx := context.Background()
-	enc, err := GetAudiosOnlyEnc(&ctx, []string{TestAudio1}, "", out)
+	enc, err := GetAudiosOnlyEnc(&ctx, []string{TestAudio1}, "", out, nil)
 	err = runEncoding(t, enc)
 	assert.Nil(t, err)
 }
@@ -89,7 +93,7 @@ func TestEncodeBox_getAudiosOnly_MultipleAudios(t *testing.T) {
 	dir, out := Setup(t)
 	defer Teardown(t, dir)
 	ctx := context.Background()
-	enc, err := GetAudiosOnlyEnc(&ctx, []string{TestAudio1, TestAudio2, TestAudio3}, "", out)
+	enc, err := GetAudiosOnlyEnc(&ctx, []string{TestAudio1, TestAudio2, TestAudio3}, "", out, nil)
 	err = runEncoding(t, enc)
 	assert.Nil(t, err)
 }
@@ -98,7 +102,7 @@ func TestEncodeBox_getAudiosOnly_SingleAudio_SideChannel(t *testing.T) {
 	dir, out := Setup(t)
 	defer Teardown(t, dir)
 	ctx := context.Background()
-	enc, err := GetAudiosOnlyEnc(&ctx, []string{TestDialog}, TestBackground, out)
+	enc, err := GetAudiosOnlyEnc(&ctx, []string{TestDialog}, TestBackground, out, nil)
 	err = runEncoding(t, enc)
 	assert.Nil(t, err)
 }
@@ -108,7 +112,77 @@ func TestEncodeBox_getAudiosOnly_MultipleAudios_SideChannel(t *testing.T) {
 	dir, out := Setup(t)
 	defer Teardown(t, dir)
 	ctx := context.Background()
-	enc, err := GetAudiosOnlyEnc(&ctx, []string{TestAudio1, TestAudio2, TestAudio3}, TestAudio2, out)
+	enc, err := GetAudiosOnlyEnc(&ctx, []string{TestAudio1, TestAudio2, TestAudio3}, TestAudio2, out, nil)
+	err = runEncoding(t, enc)
+	assert.Nil(t, err)
+}
+
+// Testing an encoding using a two-pass EBU R128 normalization
+func TestEncodeBox_getAudiosOnly_TwoPassLoudness(t *testing.T) {
+	dir, out := Setup(t)
+	defer Teardown(t, dir)
+	ctx := context.Background()
+	opt := &PresetOptions{Loudness: LoudnessOptions{Mode: TwoPassLoudness, Targets: filtergraph.BroadcastLoudness()}}
+	enc, err := GetAudiosOnlyEnc(&ctx, []string{TestAudio1, TestAudio2}, "", out, opt)
+	// No encoder is returned if the analysis pass failed
+	if !assert.Nil(t, err) {
+		t.FailNow()
+	}
+	err = runEncoding(t, enc)
+	assert.Nil(t, err)
+}
+
+// Testing an encoding with speakers tracks mixed together, each one with its own processing
+func TestEncodeBox_getAudiosImage_MixedProcessedTracks(t *testing.T) {
+	dir, out := Setup(t)
+	defer Teardown(t, dir)
+	ctx := context.Background()
+	opt := &PresetOptions{
+		AudioLayout: MixLayout,
+		Tracks: []*TrackProcessing{
+			{Highpass: 80, Denoise: &filtergraph.DenoiseParams{}, Compressor: &filtergraph.CompressorParams{}},
+			nil,
+			{Gate: &filtergraph.GateParams{}, Gain: -3},
+		},
+	}
+	enc, err := GetAudiosImageEnc(&ctx, TestImage, []string{TestAudio1, TestDialog, TestAudio3}, out, opt)
+	assert.Nil(t, err)
+	err = runEncoding(t, enc)
+	assert.Nil(t, err)
+}
+
+// Testing an encoding with silences removed from the main audio track
+func TestEncodeBox_getAudiosOnly_SilenceCuts(t *testing.T) {
+	dir, out := Setup(t)
+	defer Teardown(t, dir)
+	ctx := context.Background()
+	opt := &PresetOptions{}
+	detection, err := DetectSilences(&ctx, []string{TestDialog}, opt, SilenceOptions{MinDuration: 500 * time.Millisecond})
+	if !assert.Nil(t, err) {
+		t.FailNow()
+	}
+	opt.Cuts = detection.Cuts(true, time.Second)
+	enc, err := GetAudiosOnlyEnc(&ctx, []string{TestDialog}, "", out, opt)
+	assert.Nil(t, err)
+	err = runEncoding(t, enc)
+	assert.Nil(t, err)
+}
+
+// Testing an encoding with a chapter for each audio part
+func TestEncodeBox_getAudiosImage_Chapters(t *testing.T) {
+	dir, out := Setup(t)
+	defer Teardown(t, dir)
+	ctx := context.Background()
+	chapters, err := AudioPartsChapters([]string{TestAudio1, TestDialog})
+	require.NoError(t, err)
+	// The second part starts exactly when the first one ends
+	first, err := GetDuration(TestAudio1)
+	require.NoError(t, err)
+	assert.Equal(t, first, chapters[1].Start)
+	chapters[0].End = chapters[1].Start
+	chapters[1].End = chapters[1].Start + 22*time.Second
+	enc, err := GetAudiosImageEnc(&ctx, TestImage, []string{TestAudio1, TestDialog}, out, &PresetOptions{Chapters: chapters})
+	assert.Nil(t, err)
 	err = runEncoding(t, enc)
 	assert.Nil(t, err)
 }
@@ -123,7 +197,11 @@ func Teardown(t *testing.T, dir string) {
 
 // Start the specified encoder and waits fo it to finish running.
 // Return an error if the encoder did not succeed, nil otherwise
-func runEncoding(t *testing.T, enc *Encoder) error {
+func runEncoding(t testing.TB, enc *Encoder) error {
+	if enc == nil {
+		// The encoder setup failed, there is nothing to run
+		t.Fatal("no encoder to run")
+	}
 	cmd := enc.GetCommandLine()
 	fmt.Println(cmd)
 	go enc.Start()
@@ -139,3 +217,290 @@ func runEncoding(t *testing.T, enc *Encoder) error {
 		}
 	}
 }
+
+// Testing an encoding with burnt subtitles
+func TestEncodeBox_GetAudiosVideo_BurntSubtitles(t *testing.T) {
+	dir, out := Setup(t)
+	defer Teardown(t, dir)
+	ctx := context.Background()
+	subs := path.Join(dir, "sub.srt")
+	err := os.WriteFile(subs, []byte("1\n00:00:00,000 --> 00:00:02,000\nHello there\n"), 0644)
+	assert.Nil(t, err)
+	enc, err := GetAudiosVideoEnc(&ctx, TestVideo, []string{TestAudio1}, out, &PresetOptions{
+		Subtitles: &SubtitleOptions{Path: subs, Burn: true, Style: filtergraph.SubtitleStyle{FontSize: 28}},
+	})
+	assert.Nil(t, err)
+	err = runEncoding(t, enc)
+	assert.Nil(t, err)
+}
+
+// Testing an encoding with a soft subtitle track
+func TestEncodeBox_GetAudiosVideo_SoftSubtitles(t *testing.T) {
+	dir, out := Setup(t)
+	defer Teardown(t, dir)
+	ctx := context.Background()
+	subs := path.Join(dir, "sub.srt")
+	err := os.WriteFile(subs, []byte("1\n00:00:00,000 --> 00:00:02,000\nHello there\n"), 0644)
+	assert.Nil(t, err)
+	enc, err := GetAudiosVideoEnc(&ctx, TestVideo, []string{TestAudio1}, out, &PresetOptions{
+		Subtitles: &SubtitleOptions{Path: subs, Language: "eng"},
+	})
+	assert.Nil(t, err)
+	err = runEncoding(t, enc)
+	assert.Nil(t, err)
+}
+
+// Testing an encoding with a waveform over the image
+func TestEncodeBox_getAudiosImage_Waveform(t *testing.T) {
+	dir, out := Setup(t)
+	defer Teardown(t, dir)
+	ctx := context.Background()
+	enc, err := GetAudiosImageEnc(&ctx, TestImage, []string{TestAudio1}, out, &PresetOptions{
+		Visualization: &VisualizationOptions{VisualizationParams: filtergraph.VisualizationParams{Position: filtergraph.Bottom}},
+	})
+	assert.Nil(t, err)
+	err = runEncoding(t, enc)
+	assert.Nil(t, err)
+}
+
+// Testing an encoding with a spectrum over a colored background
+func TestEncodeBox_getAudiosOnly_Spectrum(t *testing.T) {
+	dir, out := Setup(t)
+	defer Teardown(t, dir)
+	ctx := context.Background()
+	enc, err := GetAudiosOnlyEnc(&ctx, []string{TestAudio1, TestAudio2}, TestBackground, out, &PresetOptions{
+		Visualization: &VisualizationOptions{
+			VisualizationParams: filtergraph.VisualizationParams{Mode: filtergraph.Spectrum, Width: 1280, Height: 720},
+			Background:          "#102030",
+		},
+	})
+	assert.Nil(t, err)
+	err = runEncoding(t, enc)
+	assert.Nil(t, err)
+}
+
+// Testing an encoding with a title card and a persistent timestamp
+func TestEncodeBox_GetAudiosVideo_Texts(t *testing.T) {
+	dir, out := Setup(t)
+	defer Teardown(t, dir)
+	ctx := context.Background()
+	font := path.Join(ResPath, "../fonts/DejaVuSans.ttf")
+	enc, err := GetAudiosVideoEnc(&ctx, TestVideo, []string{TestAudio1}, out, &PresetOptions{
+		Texts: []filtergraph.TextParams{
+			{Text: "Episode 1 : it's 100% live", FontFile: font, Position: filtergraph.MiddleCenter, End: 3 * time.Second},
+			{Text: "{timestamp}", FontFile: font, FontSize: 18, BoxColor: "black@0.5", Position: filtergraph.TopRight},
+		},
+	})
+	assert.Nil(t, err)
+	err = runEncoding(t, enc)
+	assert.Nil(t, err)
+}
+
+// Testing an encoding with an intro and an outro around an image and audios
+func TestEncodeBox_getAudiosImage_IntroOutro(t *testing.T) {
+	dir, out := Setup(t)
+	defer Teardown(t, dir)
+	ctx := context.Background()
+	enc, err := GetAudiosImageEnc(&ctx, TestImage, []string{TestAudio1, TestAudio2}, out, &PresetOptions{
+		Intro: TestVideo,
+		Outro: TestVideo,
+	})
+	assert.Nil(t, err)
+	err = runEncoding(t, enc)
+	assert.Nil(t, err)
+}
+
+// Testing an encoding with an intro before a video
+func TestEncodeBox_GetAudiosVideo_Intro(t *testing.T) {
+	dir, out := Setup(t)
+	defer Teardown(t, dir)
+	ctx := context.Background()
+	enc, err := GetAudiosVideoEnc(&ctx, TestVideo, []string{TestAudio1}, out, &PresetOptions{Intro: TestVideo})
+	assert.Nil(t, err)
+	err = runEncoding(t, enc)
+	assert.Nil(t, err)
+}
+
+// Testing a vertical encoding, the image being blurred to fill the canvas
+func TestEncodeBox_getAudiosImage_VerticalBlurPad(t *testing.T) {
+	dir, out := Setup(t)
+	defer Teardown(t, dir)
+	ctx := context.Background()
+	enc, err := GetAudiosImageEnc(&ctx, TestImage, []string{TestAudio1}, out, &PresetOptions{
+		Canvas: filtergraph.Canvas{Width: 1080, Height: 1920, FrameRate: 30, Fit: filtergraph.BlurPad},
+	})
+	assert.Nil(t, err)
+	err = runEncoding(t, enc)
+	assert.Nil(t, err)
+}
+
+// Testing a square encoding, the video being cropped
+func TestEncodeBox_GetAudiosVideo_SquareCrop(t *testing.T) {
+	dir, out := Setup(t)
+	defer Teardown(t, dir)
+	ctx := context.Background()
+	enc, err := GetAudiosVideoEnc(&ctx, TestVideo, []string{TestAudio1}, out, &PresetOptions{
+		Canvas: filtergraph.Canvas{Width: 720, Height: 720, FrameRate: 25, Fit: filtergraph.Crop},
+	})
+	assert.Nil(t, err)
+	err = runEncoding(t, enc)
+	assert.Nil(t, err)
+}
+
+// Testing a slideshow with transitions and a zoom on each image
+func TestEncodeBox_getAudiosSlideshow(t *testing.T) {
+	dir, out := Setup(t)
+	defer Teardown(t, dir)
+	ctx := context.Background()
+	enc, err := GetAudiosSlideshowEnc(&ctx, []string{TestImage, TestImage, TestImage}, []string{TestAudio1}, out, &PresetOptions{
+		Slideshow: SlideshowOptions{Transition: "fade", KenBurns: true},
+	})
+	assert.Nil(t, err)
+	err = runEncoding(t, enc)
+	assert.Nil(t, err)
+}
+
+// Comparing the encoding time of an image video with and without the still image fast path
+func BenchmarkGetAudiosImageEnc_Still(b *testing.B) {
+	benchmarkStill(b, func(ctx *context.Context, out string, opt *PresetOptions) (*Encoder, error) {
+		return GetAudiosImageEnc(ctx, TestImage, []string{TestAudio1}, out, opt)
+	})
+}
+
+// Comparing the encoding time of a plain background video with and without the still image fast path
+func BenchmarkGetAudiosOnlyEnc_Still(b *testing.B) {
+	benchmarkStill(b, func(ctx *context.Context, out string, opt *PresetOptions) (*Encoder, error) {
+		return GetAudiosOnlyEnc(ctx, []string{TestAudio1}, "", out, opt)
+	})
+}
+
+// Run the preset built by makeEnc, once at StillFrameRate and once at the full framerate
+func benchmarkStill(b *testing.B, makeEnc func(ctx *context.Context, out string, opt *PresetOptions) (*Encoder, error)) {
+	for _, fullFrameRate := range []bool{false, true} {
+		b.Run(fmt.Sprintf("FullFrameRate=%t", fullFrameRate), func(b *testing.B) {
+			dir := b.TempDir()
+			for i := 0; i < b.N; i++ {
+				ctx := context.Background()
+				// FFmpeg doesn't overwrite an existing output, each iteration needs its own
+				out := path.Join(dir, fmt.Sprintf("out%d.mp4", i))
+				enc, err := makeEnc(&ctx, out, &PresetOptions{FullFrameRate: fullFrameRate})
+				if err != nil {
+					b.Fatal(err)
+				}
+				if err := runEncoding(b, enc); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// Testing that an untouched audio track already in the output format is copied instead of being re-encoded
+func TestEncodeBox_getAudiosImage_AudioCopy(t *testing.T) {
+	dir, out := Setup(t)
+	defer Teardown(t, dir)
+	ctx := context.Background()
+	compliant := path.Join(dir, "compliant.m4a")
+	builder := Builder{}
+	builder.AddInput(&FileInput{Path: TestAudio1}).
+		AddOutputOption("-vn").AddOutputOption("-c:a", "aac").AddOutputOption("-ar", "44100").AddOutputOption("-ac", "2").
+		SetOutput(compliant)
+	_, err := runFFmpeg(&ctx, &builder)
+	if !assert.Nil(t, err) {
+		t.FailNow()
+	}
+
+	opt := &PresetOptions{Loudness: LoudnessOptions{Mode: NoLoudness}}
+	enc, err := GetAudiosImageEnc(&ctx, TestImage, []string{compliant}, out, opt)
+	assert.Nil(t, err)
+	assert.Contains(t, enc.GetCommandLine(), "-map 1:a")
+	assert.Contains(t, enc.GetCommandLine(), "-c:a copy")
+	err = runEncoding(t, enc)
+	assert.Nil(t, err)
+
+	// Normalizing the audio requires re-encoding it
+	enc, err = GetAudiosImageEnc(&ctx, TestImage, []string{compliant}, out, nil)
+	assert.Nil(t, err)
+	assert.NotContains(t, enc.GetCommandLine(), "-c:a copy")
+}
+
+// Testing that an untouched video is copied instead of being re-encoded
+func TestEncodeBox_GetAudiosVideo_VideoCopy(t *testing.T) {
+	dir, out := Setup(t)
+	defer Teardown(t, dir)
+	ctx := context.Background()
+	enc, err := GetAudiosVideoEnc(&ctx, TestVideo, []string{TestAudio1}, out, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, &VideoDecision{Copy: true}, enc.Video)
+	assert.Contains(t, enc.GetCommandLine(), "-c:v copy")
+	err = runEncoding(t, enc)
+	assert.Nil(t, err)
+}
+
+// Testing an encoding with a video without any audio stream, such as a screen capture
+func TestEncodeBox_GetAudiosVideo_SilentVideo(t *testing.T) {
+	dir, out := Setup(t)
+	defer Teardown(t, dir)
+	ctx := context.Background()
+	silent := path.Join(dir, "silent.mp4")
+	builder := Builder{}
+	builder.AddInput(&FileInput{Path: TestVideo}).AddOutputOption("-an").AddOutputOption("-c:v", "copy").SetOutput(silent)
+	_, err := runFFmpeg(&ctx, &builder)
+	assert.Nil(t, err)
+	assert.False(t, hasAudioStream(silent))
+
+	enc, err := GetAudiosVideoEnc(&ctx, silent, []string{TestAudio1}, out, nil)
+	assert.Nil(t, err)
+	assert.NotContains(t, enc.GetCommandLine(), "amix")
+	err = runEncoding(t, enc)
+	assert.Nil(t, err)
+}
+
+// Testing that the requested audio format applies to the mix of the video audio track with the audio tracks
+func TestEncodeBox_GetAudiosVideo_AudioFormat(t *testing.T) {
+	dir, out := Setup(t)
+	defer Teardown(t, dir)
+	ctx := context.Background()
+	opt := &PresetOptions{AudioFormat: filtergraph.AudioFormat{SampleRate: filtergraph.K48, SampleFormat: filtergraph.S16, ChannelLayout: filtergraph.Mono}}
+	enc, err := GetAudiosVideoEnc(&ctx, TestVideo, []string{TestAudio1}, out, opt)
+	if !assert.Nil(t, err) {
+		t.FailNow()
+	}
+	cmd := enc.GetCommandLine()
+	// The mix negotiates its own format, the audio is resampled afterwards
+	assert.Less(t, strings.Index(cmd, "amix"), strings.LastIndex(cmd, "aformat=sample_fmts=s16:sample_rates=48000:channel_layouts=mono"))
+	err = runEncoding(t, enc)
+	assert.Nil(t, err)
+	info, err := Probe(out)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, info.Audio().Channels)
+	assert.Equal(t, 48000, info.Audio().SampleRate)
+}
+
+// Testing the concatenation of audio parts with different codecs, sample rates and channel layouts
+func TestEncodeBox_getAudiosOnly_HeterogeneousAudios(t *testing.T) {
+	dir, out := Setup(t)
+	defer Teardown(t, dir)
+	ctx := context.Background()
+	enc, err := GetAudiosOnlyEnc(&ctx, []string{TestAudio1, TestDialog, TestBackground}, "", out, nil)
+	assert.Nil(t, err)
+	err = runEncoding(t, enc)
+	assert.Nil(t, err)
+}
+
+// Testing a mono voice-only export, which must not be upmixed to stereo
+func TestEncodeBox_getAudiosOnly_MonoAudio(t *testing.T) {
+	dir, out := Setup(t)
+	defer Teardown(t, dir)
+	ctx := context.Background()
+	opt := &PresetOptions{AudioFormat: filtergraph.AudioFormat{SampleRate: filtergraph.K48, ChannelLayout: filtergraph.Mono}}
+	enc, err := GetAudiosOnlyEnc(&ctx, []string{TestAudio1, TestDialog}, "", out, opt)
+	assert.Nil(t, err)
+	assert.NotContains(t, enc.GetCommandLine(), "stereo")
+	err = runEncoding(t, enc)
+	assert.Nil(t, err)
+	info, err := Probe(out)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, info.Audio().Channels)
+	assert.Equal(t, 48000, info.Audio().SampleRate)
+}
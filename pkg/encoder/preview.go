@@ -0,0 +1,103 @@
+package encoder
+
+import (
+	"context"
+	"encode-box/pkg/encoder/filtergraph"
+	"fmt"
+	"time"
+)
+
+type PreviewFormat uint8
+
+const (
+	// Animated WebP, lighter than a GIF for the same quality. This is the default
+	PreviewWebP PreviewFormat = iota
+	// Animated GIF, for platforms not supporting WebP
+	PreviewGIF
+	// Muted H264 MP4 teaser
+	PreviewMP4
+)
+
+// Extension File extension of the format, without the dot
+func (pf PreviewFormat) Extension() string {
+	switch pf {
+	case PreviewGIF:
+		return "gif"
+	case PreviewMP4:
+		return "mp4"
+	default:
+		return "webp"
+	}
+}
+
+// PreviewOptions Part of the video used as a preview, and its appearance
+type PreviewOptions struct {
+	// Offset of the preview in the video. If the video is shorter, the preview starts at the beginning
+	Start time.Duration
+	// Length of the preview. Default to 5s
+	Duration time.Duration
+	// Width of the preview (px), the height keeps the aspect ratio. Default to 480
+	Width int
+	// Default to 12 fps for animated images, 25 fps for an MP4
+	FrameRate int
+	Format    PreviewFormat
+}
+
+// Return the options with defaults values for all unset fields
+func (po PreviewOptions) withDefaults() PreviewOptions {
+	if po.Duration == 0 {
+		po.Duration = 5 * time.Second
+	}
+	if po.Width == 0 {
+		po.Width = 480
+	}
+	if po.FrameRate == 0 {
+		po.FrameRate = 12
+		if po.Format == PreviewMP4 {
+			po.FrameRate = 25
+		}
+	}
+	return po
+}
+
+// Output options specific to the format, one argument per item
+func (po PreviewOptions) outputOptions() []string {
+	switch po.Format {
+	case PreviewGIF:
+		return []string{"-loop", "0"}
+	case PreviewMP4:
+		return []string{"-c:v", "libx264", "-preset", "veryfast", "-crf", "28", "-pix_fmt", "yuv420p", "-movflags", "+faststart"}
+	default:
+		return []string{"-c:v", "libwebp", "-quality", "75", "-loop", "0"}
+	}
+}
+
+// GeneratePreview Write a short, scaled down and muted clip of videoPath at output
+func GeneratePreview(ctx *context.Context, videoPath string, output string, opt PreviewOptions) error {
+	opt = opt.withDefaults()
+	duration, err := GetDuration(videoPath)
+	if err != nil {
+		return fmt.Errorf("could not get video duration : %w", err)
+	}
+	if opt.Start >= duration {
+		opt.Start = 0
+	}
+	builder := Builder{}
+	// Seeking before the input is way faster than decoding the video up to the preview
+	builder.AddInput(&FileInput{Path: videoPath, Options: []string{
+		"-ss", fmt.Sprintf("%.3f", opt.Start.Seconds()),
+		"-t", fmt.Sprintf("%.3f", opt.Duration.Seconds()),
+	}})
+	preview := filtergraph.NewPreviewFilter(filtergraph.NewInput("0:v"), opt.Width, opt.FrameRate, opt.Format == PreviewGIF)
+	builder.
+		SetFilterGraph(preview).
+		MapStream(preview).
+		AddOutputOption("-an").
+		AddOutputOption(opt.outputOptions()...).
+		AddOutputOption("-y").
+		SetOutput(output)
+	if out, err := runFFmpeg(ctx, &builder); err != nil {
+		return fmt.Errorf("could not generate preview : %w, %s", err, lastLine(out))
+	}
+	return nil
+}
@@ -0,0 +1,33 @@
+package encoder
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"path"
+	"testing"
+	"time"
+)
+
+func TestPreview_Defaults(t *testing.T) {
+	opt := PreviewOptions{}.withDefaults()
+	assert.Equal(t, PreviewOptions{Duration: 5 * time.Second, Width: 480, FrameRate: 12, Format: PreviewWebP}, opt)
+	// A teaser should be as smooth as the video itself
+	assert.Equal(t, 25, PreviewOptions{Format: PreviewMP4}.withDefaults().FrameRate)
+}
+
+func TestPreview_Extension(t *testing.T) {
+	assert.Equal(t, "webp", PreviewWebP.Extension())
+	assert.Equal(t, "gif", PreviewGIF.Extension())
+	assert.Equal(t, "mp4", PreviewMP4.Extension())
+}
+
+// Testing the generation of a preview in each format
+func TestPreview_GeneratePreview(t *testing.T) {
+	dir := t.TempDir()
+	ctx := context.Background()
+	for _, format := range []PreviewFormat{PreviewWebP, PreviewGIF, PreviewMP4} {
+		output := path.Join(dir, "preview."+format.Extension())
+		err := GeneratePreview(&ctx, TestVideo, output, PreviewOptions{Start: time.Second, Duration: 2 * time.Second, Format: format})
+		assert.Nil(t, err)
+	}
+}
@@ -1,25 +1,239 @@
 package encoder
 
 import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"os/exec"
 	"strconv"
 	"strings"
 	"time"
 )
 
-func GetDuration(path string) (time.Duration, error) {
-	// ffprobe -v error -show_entries format=duration -of default=noprint_wrappers=1:nokey=1 input.mp4
-	arg := strings.Split("-v error -show_entries format=duration -of default=noprint_wrappers=1:nokey=1", " ")
+// MediaInfo Container and streams of a media file, as reported by ffprobe
+type MediaInfo struct {
+	Format  FormatInfo
+	Streams []StreamInfo
+}
+
+// FormatInfo Container of a media file
+type FormatInfo struct {
+	// Short names of the container, separated by commas ("mov,mp4,m4a,3gp,3g2,mj2")
+	Name     string
+	Duration time.Duration
+	// Overall bitrate (bit/s). 0 if unknown
+	BitRate int64
+	// Size of the file (bytes)
+	Size int64
+}
+
+type StreamType string
+
+const (
+	VideoStream    StreamType = "video"
+	AudioStream    StreamType = "audio"
+	SubtitleStream StreamType = "subtitle"
+)
+
+// StreamInfo A single stream of a media file. Fields not applicable to the stream type are left to 0
+type StreamInfo struct {
+	// Index of the stream in the file
+	Index int
+	Type  StreamType
+	// Codec name (h264, aac, mjpeg...)
+	Codec string
+	// Video only. Size of the frames (px), before rotation
+	Width  int
+	Height int
+	// Video only. Number of frames per second
+	FrameRate float64
+	// Video only. Rotation applied when displaying the video, in degrees
+	Rotation int
+	// Video only. True if the stream is a single image attached to the file, such as the cover of an audio file
+	AttachedPic bool
+	// Audio only. Number of samples per second (Hz)
+	SampleRate int
+	// Audio only
+	Channels int
+	// Audio only. Representation of each sample ("fltp", "s16"...)
+	SampleFormat string
+	// Bitrate of the stream (bit/s). 0 if unknown
+	BitRate  int64
+	Duration time.Duration
+}
+
+// Video Return the first actual video stream, not counting attached pictures. Nil if there is none
+func (mi *MediaInfo) Video() *StreamInfo {
+	for i, s := range mi.Streams {
+		if s.Type == VideoStream && !s.AttachedPic {
+			return &mi.Streams[i]
+		}
+	}
+	return nil
+}
+
+// Audio Return the first audio stream. Nil if there is none
+func (mi *MediaInfo) Audio() *StreamInfo {
+	for i, s := range mi.Streams {
+		if s.Type == AudioStream {
+			return &mi.Streams[i]
+		}
+	}
+	return nil
+}
+
+// HasVideo Return true if the media has an actual video stream
+func (mi *MediaInfo) HasVideo() bool {
+	return mi.Video() != nil
+}
+
+// HasAudio Return true if the media has an audio stream
+func (mi *MediaInfo) HasAudio() bool {
+	return mi.Audio() != nil
+}
+
+// Probe Inspect the container and streams of the media at path
+func Probe(path string) (*MediaInfo, error) {
+	// ffprobe -v error -print_format json -show_format -show_streams input.mp4
+	arg := strings.Split("-v error -print_format json -show_format -show_streams", " ")
 	arg = append(arg, path)
 	cmd := exec.Command("ffprobe", arg...)
 	out, err := cmd.Output()
 	if err != nil {
-		return 0, err
+		return nil, probeError(path, err)
 	}
+	return parseProbeOutput(out)
+}
 
-	dur, err := strconv.ParseFloat(strings.TrimSpace(string(out)), 64)
+// Wrap an ffprobe failure. The exit status alone says nothing, the reason is written on stderr
+func probeError(path string, err error) error {
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && len(bytes.TrimSpace(exitErr.Stderr)) > 0 {
+		return fmt.Errorf("could not probe %s : %w : %s", path, err, bytes.TrimSpace(exitErr.Stderr))
+	}
+	return fmt.Errorf("could not probe %s : %w", path, err)
+}
+
+// Raw ffprobe JSON output. Most numbers are serialized as strings
+type probeOutput struct {
+	Format struct {
+		FormatName string `json:"format_name"`
+		Duration   string `json:"duration"`
+		BitRate    string `json:"bit_rate"`
+		Size       string `json:"size"`
+	} `json:"format"`
+	Streams []struct {
+		Index        int    `json:"index"`
+		CodecType    string `json:"codec_type"`
+		CodecName    string `json:"codec_name"`
+		Width        int    `json:"width"`
+		Height       int    `json:"height"`
+		AvgFrameRate string `json:"avg_frame_rate"`
+		RFrameRate   string `json:"r_frame_rate"`
+		SampleRate   string `json:"sample_rate"`
+		Channels     int    `json:"channels"`
+		SampleFmt    string `json:"sample_fmt"`
+		BitRate      string `json:"bit_rate"`
+		Duration     string `json:"duration"`
+		Disposition  struct {
+			AttachedPic int `json:"attached_pic"`
+		} `json:"disposition"`
+		Tags struct {
+			Rotate string `json:"rotate"`
+		} `json:"tags"`
+		SideDataList []struct {
+			Rotation *int `json:"rotation"`
+		} `json:"side_data_list"`
+	} `json:"streams"`
+}
+
+// Convert ffprobe JSON output into a MediaInfo
+func parseProbeOutput(out []byte) (*MediaInfo, error) {
+	var raw probeOutput
+	if err := json.Unmarshal(out, &raw); err != nil {
+		return nil, fmt.Errorf("invalid ffprobe output : %w", err)
+	}
+	info := &MediaInfo{
+		Format: FormatInfo{
+			Name:     raw.Format.FormatName,
+			Duration: parseSeconds(raw.Format.Duration),
+			BitRate:  parseInt(raw.Format.BitRate),
+			Size:     parseInt(raw.Format.Size),
+		},
+		Streams: make([]StreamInfo, 0, len(raw.Streams)),
+	}
+	for _, s := range raw.Streams {
+		stream := StreamInfo{
+			Index:        s.Index,
+			Type:         StreamType(s.CodecType),
+			Codec:        s.CodecName,
+			Width:        s.Width,
+			Height:       s.Height,
+			AttachedPic:  s.Disposition.AttachedPic == 1,
+			SampleRate:   int(parseInt(s.SampleRate)),
+			Channels:     s.Channels,
+			SampleFormat: s.SampleFmt,
+			BitRate:      parseInt(s.BitRate),
+			Duration:     parseSeconds(s.Duration),
+		}
+		if stream.Type == VideoStream {
+			// The average framerate is unknown for some containers, the base one is always set
+			stream.FrameRate = parseRational(s.AvgFrameRate)
+			if stream.FrameRate == 0 {
+				stream.FrameRate = parseRational(s.RFrameRate)
+			}
+			// Recent FFmpeg versions report the rotation in the display matrix, older ones in a tag
+			stream.Rotation = int(parseInt(s.Tags.Rotate))
+			for _, sd := range s.SideDataList {
+				if sd.Rotation != nil {
+					stream.Rotation = *sd.Rotation
+				}
+			}
+		}
+		info.Streams = append(info.Streams, stream)
+	}
+	return info, nil
+}
+
+// Parse a number of seconds, as a duration. Unknown values ("N/A", "") are 0
+func parseSeconds(value string) time.Duration {
+	seconds, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0
+	}
+	return time.Duration(seconds * float64(time.Second))
+}
+
+// Parse an integer. Unknown values ("N/A", "") are 0
+func parseInt(value string) int64 {
+	i, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return 0
+	}
+	return i
+}
+
+// Parse a rational number ("30000/1001"). Unknown values ("0/0", "") are 0
+func parseRational(value string) float64 {
+	num, den, found := strings.Cut(value, "/")
+	if !found {
+		f, _ := strconv.ParseFloat(value, 64)
+		return f
+	}
+	n, err1 := strconv.ParseFloat(num, 64)
+	d, err2 := strconv.ParseFloat(den, 64)
+	if err1 != nil || err2 != nil || d == 0 {
+		return 0
+	}
+	return n / d
+}
+
+// GetDuration Return the exact duration of the media at path
+func GetDuration(path string) (time.Duration, error) {
+	info, err := Probe(path)
 	if err != nil {
 		return 0, err
 	}
-	return time.Duration(int(dur)) * time.Second, nil
+	return info.Format.Duration, nil
 }
@@ -2,38 +2,40 @@ package encoder
 
 import (
 	"github.com/stretchr/testify/assert"
+	"os/exec"
 	"testing"
+	"time"
 )
 
 func TestProbe_GetDuration_Video(t *testing.T) {
 	duration, err := GetDuration(TestVideo)
 	assert.NoError(t, err)
-	assert.Equal(t, 10.0, duration.Seconds())
+	assert.Equal(t, 10.0, duration.Truncate(time.Second).Seconds())
 }
 
 func TestProbe_GetDuration_AudioShort(t *testing.T) {
 	duration, err := GetDuration(TestAudio1)
 	assert.NoError(t, err)
-	assert.Equal(t, 3.0, duration.Seconds())
+	assert.Equal(t, 3.0, duration.Truncate(time.Second).Seconds())
 }
 
 func TestProbe_GetDuration_AudioLong(t *testing.T) {
 	duration, err := GetDuration(TestDialog)
 	assert.NoError(t, err)
-	assert.Equal(t, 22.0, duration.Seconds())
+	assert.Equal(t, 22.0, duration.Truncate(time.Second).Seconds())
 }
 
 func TestProbe_GetDuration_AudioVeryLong(t *testing.T) {
 	duration, err := GetDuration(TestBackground)
 	assert.NoError(t, err)
-	assert.Equal(t, 10.0*60, duration.Seconds())
+	assert.Equal(t, 10.0*60, duration.Truncate(time.Second).Seconds())
 }
 
 // Ffprobe actually return a value for images, it should be 0
 func TestProbe_GetDuration_Image(t *testing.T) {
 	duration, err := GetDuration(TestImage)
 	assert.NoError(t, err)
-	assert.Equal(t, 0.0, duration.Seconds())
+	assert.Equal(t, 0.0, duration.Truncate(time.Second).Seconds())
 }
 
 func TestProbe_GetDuration_NonExisting(t *testing.T) {
@@ -41,3 +43,74 @@ func TestProbe_GetDuration_NonExisting(t *testing.T) {
 	assert.Error(t, err)
 	assert.Zero(t, duration)
 }
+
+func TestProbe_ParseProbeOutput(t *testing.T) {
+	out := `{
+		"streams": [
+			{"index": 0, "codec_name": "h264", "codec_type": "video", "width": 1920, "height": 1080,
+			 "r_frame_rate": "30000/1001", "avg_frame_rate": "30000/1001", "bit_rate": "4000000", "duration": "10.010000",
+			 "disposition": {"attached_pic": 0}, "side_data_list": [{"side_data_type": "Display Matrix", "rotation": -90}]},
+			{"index": 1, "codec_name": "aac", "codec_type": "audio", "sample_rate": "48000", "channels": 2, "sample_fmt": "fltp",
+			 "r_frame_rate": "0/0", "avg_frame_rate": "0/0", "bit_rate": "128000", "duration": "10.000000"},
+			{"index": 2, "codec_name": "mjpeg", "codec_type": "video", "width": 600, "height": 600,
+			 "r_frame_rate": "90000/1", "avg_frame_rate": "0/0", "disposition": {"attached_pic": 1}}
+		],
+		"format": {"format_name": "mov,mp4,m4a,3gp,3g2,mj2", "duration": "10.010000", "size": "5242880", "bit_rate": "4190000"}
+	}`
+	info, err := parseProbeOutput([]byte(out))
+	assert.NoError(t, err)
+	assert.Equal(t, FormatInfo{Name: "mov,mp4,m4a,3gp,3g2,mj2", Duration: 10010 * time.Millisecond, BitRate: 4190000, Size: 5242880}, info.Format)
+	assert.Len(t, info.Streams, 3)
+	assert.Equal(t, StreamInfo{
+		Index: 0, Type: VideoStream, Codec: "h264", Width: 1920, Height: 1080, FrameRate: 30000.0 / 1001,
+		Rotation: -90, BitRate: 4000000, Duration: 10010 * time.Millisecond,
+	}, *info.Video())
+	assert.Equal(t, StreamInfo{
+		Index: 1, Type: AudioStream, Codec: "aac", SampleRate: 48000, Channels: 2, SampleFormat: "fltp", BitRate: 128000, Duration: 10 * time.Second,
+	}, *info.Audio())
+	assert.True(t, info.Streams[2].AttachedPic)
+	assert.Equal(t, 90000.0, info.Streams[2].FrameRate)
+}
+
+// An audio file with a cover has a video stream, which is not an actual video
+func TestProbe_ParseProbeOutput_Cover(t *testing.T) {
+	out := `{
+		"streams": [
+			{"index": 0, "codec_name": "mp3", "codec_type": "audio", "sample_rate": "44100", "channels": 1},
+			{"index": 1, "codec_name": "png", "codec_type": "video", "disposition": {"attached_pic": 1}, "tags": {"rotate": "0"}}
+		],
+		"format": {"format_name": "mp3", "duration": "N/A"}
+	}`
+	info, err := parseProbeOutput([]byte(out))
+	assert.NoError(t, err)
+	assert.True(t, info.HasAudio())
+	assert.False(t, info.HasVideo())
+	assert.Zero(t, info.Format.Duration)
+}
+
+func TestProbe_ParseProbeOutput_Invalid(t *testing.T) {
+	_, err := parseProbeOutput([]byte("Invalid data found when processing input"))
+	assert.Error(t, err)
+}
+
+func TestProbe_ProbeError_Stderr(t *testing.T) {
+	_, err := exec.Command("sh", "-c", "echo 'Invalid data found when processing input' >&2; exit 1").Output()
+	err = probeError("a.mp4", err)
+	assert.Contains(t, err.Error(), "could not probe a.mp4")
+	assert.Contains(t, err.Error(), "Invalid data found when processing input")
+}
+
+func TestProbe_Probe_Video(t *testing.T) {
+	info, err := Probe(TestVideo)
+	assert.NoError(t, err)
+	assert.True(t, info.HasVideo())
+	assert.True(t, info.HasAudio())
+	assert.Equal(t, 10.0, info.Format.Duration.Truncate(time.Second).Seconds())
+}
+
+func TestProbe_Probe_Image(t *testing.T) {
+	info, err := Probe(TestImage)
+	assert.NoError(t, err)
+	assert.Equal(t, "mjpeg", info.Video().Codec)
+	assert.False(t, info.HasAudio())
+}
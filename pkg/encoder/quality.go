@@ -0,0 +1,115 @@
+package encoder
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// QualityProfile Trade-off between the encoding speed and the quality/size of the output
+type QualityProfile uint8
+
+const (
+	// Balanced speed and quality. This is the default
+	StandardQuality QualityProfile = iota
+	// Fastest encoding, for previews and internal reviews
+	DraftQuality
+	// Slowest encoding, the output is kept as a reference
+	ArchiveQuality
+)
+
+// VideoCodec Codec of the output video track
+type VideoCodec uint8
+
+const (
+	// H264 (libx264). This is the default
+	H264 VideoCodec = iota
+	// H265/HEVC (libx265)
+	H265
+	// VP9 (libvpx-vp9)
+	VP9
+	// AV1 (libsvtav1)
+	AV1
+)
+
+// Name of the codec, as reported by ffprobe
+func (vc VideoCodec) probeName() string {
+	switch vc {
+	case H265:
+		return "hevc"
+	case VP9:
+		return "vp9"
+	case AV1:
+		return "av1"
+	default:
+		return "h264"
+	}
+}
+
+// QualityOptions How the output video track is encoded
+type QualityOptions struct {
+	Profile QualityProfile
+	Codec   VideoCodec
+}
+
+// Encoder settings of a profile. The same profile doesn't mean the same settings across codecs,
+// as each encoder has its own scale
+type codecSettings struct {
+	// Speed preset, meaning depends on the codec
+	preset string
+	crf    int
+	// Maximum time between two keyframes (s). Expressed in seconds rather than frames, as
+	// the framerate depends on the inputs
+	keyframeInterval int
+}
+
+var qualitySettings = map[VideoCodec]map[QualityProfile]codecSettings{
+	H264: {
+		DraftQuality:    {"veryfast", 28, 10},
+		StandardQuality: {"medium", 23, 5},
+		ArchiveQuality:  {"slow", 18, 2},
+	},
+	H265: {
+		DraftQuality:    {"veryfast", 32, 10},
+		StandardQuality: {"medium", 28, 5},
+		ArchiveQuality:  {"slow", 22, 2},
+	},
+	// For VP9, the preset is the cpu-used value
+	VP9: {
+		DraftQuality:    {"8", 40, 10},
+		StandardQuality: {"4", 33, 5},
+		ArchiveQuality:  {"1", 24, 2},
+	},
+	AV1: {
+		DraftQuality:    {"12", 40, 10},
+		StandardQuality: {"8", 35, 5},
+		ArchiveQuality:  {"4", 28, 2},
+	},
+}
+
+// Return the output options encoding the video track with the codec and profile, one argument per item.
+// stillImage is true when the video is a single static image, which some encoders can optimize for
+func (qo QualityOptions) outputOptions(stillImage bool) []string {
+	s := qualitySettings[qo.Codec][qo.Profile]
+	crf := strconv.Itoa(s.crf)
+	keyframes := []string{"-force_key_frames", fmt.Sprintf("expr:gte(t,n_forced*%d)", s.keyframeInterval)}
+	switch qo.Codec {
+	case H265:
+		// The hvc1 tag is required by Apple players
+		return append(append([]string{"-c:v", "libx265", "-preset", s.preset, "-crf", crf}, keyframes...), "-tag:v", "hvc1")
+	case VP9:
+		deadline := "good"
+		if qo.Profile == DraftQuality {
+			deadline = "realtime"
+		}
+		// A zero bitrate is required for the crf to be used as a constant quality
+		return append([]string{"-c:v", "libvpx-vp9", "-deadline", deadline, "-cpu-used", s.preset, "-crf", crf, "-b:v", "0", "-row-mt", "1"}, keyframes...)
+	case AV1:
+		return append([]string{"-c:v", "libsvtav1", "-preset", s.preset, "-crf", crf}, keyframes...)
+	default:
+		opts := append([]string{"-c:v", "libx264", "-preset", s.preset, "-crf", crf}, keyframes...)
+		if stillImage {
+			opts = append(opts, "-tune", "stillimage")
+		}
+		return opts
+	}
+}
@@ -0,0 +1,37 @@
+package encoder
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestQuality_Default(t *testing.T) {
+	assert.Equal(t,
+		[]string{"-c:v", "libx264", "-preset", "medium", "-crf", "23", "-force_key_frames", "expr:gte(t,n_forced*5)"},
+		QualityOptions{}.outputOptions(false))
+	// Only x264 has a tune for static images
+	assert.Equal(t, []string{"-tune", "stillimage"}, QualityOptions{}.outputOptions(true)[8:])
+	assert.NotContains(t, QualityOptions{Codec: H265}.outputOptions(true), "-tune")
+}
+
+func TestQuality_Codecs(t *testing.T) {
+	assert.Equal(t,
+		[]string{"-c:v", "libx265", "-preset", "slow", "-crf", "22", "-force_key_frames", "expr:gte(t,n_forced*2)", "-tag:v", "hvc1"},
+		QualityOptions{Profile: ArchiveQuality, Codec: H265}.outputOptions(false))
+	assert.Equal(t,
+		[]string{"-c:v", "libvpx-vp9", "-deadline", "realtime", "-cpu-used", "8", "-crf", "40", "-b:v", "0", "-row-mt", "1", "-force_key_frames", "expr:gte(t,n_forced*10)"},
+		QualityOptions{Profile: DraftQuality, Codec: VP9}.outputOptions(false))
+	assert.Equal(t,
+		[]string{"-c:v", "libsvtav1", "-preset", "8", "-crf", "35", "-force_key_frames", "expr:gte(t,n_forced*5)"},
+		QualityOptions{Codec: AV1}.outputOptions(false))
+}
+
+// All profiles must be defined for all codecs
+func TestQuality_AllSettings(t *testing.T) {
+	for _, codec := range []VideoCodec{H264, H265, VP9, AV1} {
+		for _, profile := range []QualityProfile{StandardQuality, DraftQuality, ArchiveQuality} {
+			_, ok := qualitySettings[codec][profile]
+			assert.True(t, ok, "codec %d, profile %d", codec, profile)
+		}
+	}
+}
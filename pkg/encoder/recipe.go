@@ -0,0 +1,352 @@
+package encoder
+
+import (
+	"bytes"
+	"context"
+	"encode-box/pkg/encoder/filtergraph"
+	"errors"
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Recipe A named encoding layout described in a YAML (or JSON) file, as an alternative to the presets.
+// A recipe declares the FFmpeg inputs built from the assets, a filter chain, and the output
+type Recipe struct {
+	// Name requests use to reference the recipe. Default to the file name, without extension
+	Name        string `yaml:"name"`
+	Description string `yaml:"description"`
+	// Number of assets of each role the recipe accepts, either "N", "N+" (at least N) or "N-M".
+	// Roles not listed are refused
+	Accepts map[string]string `yaml:"accepts"`
+	// FFmpeg inputs, in order
+	Inputs []RecipeInput `yaml:"inputs"`
+	// Filters of the filtergraph. A filter can only use the inputs and the outputs of the previous filters
+	Filters []RecipeFilter `yaml:"filters"`
+	Output  RecipeOutput   `yaml:"output"`
+
+	// Parsed Accepts
+	bounds map[string]cardinality
+}
+
+// Asset roles a recipe can use
+const (
+	VideoRole     = "video"
+	AudioRole     = "audio"
+	SideAudioRole = "sideAudio"
+	ImageRole     = "image"
+	SlideRole     = "slide"
+	SubtitleRole  = "subtitle"
+	IntroRole     = "intro"
+	OutroRole     = "outro"
+)
+
+var recipeRoles = []string{VideoRole, AudioRole, SideAudioRole, ImageRole, SlideRole, SubtitleRole, IntroRole, OutroRole}
+
+// RecipeInput Either one FFmpeg input per asset of a role, or a single generated (lavfi) input
+type RecipeInput struct {
+	// Name used by filters and output to reference the input. Default to the role
+	Name string `yaml:"name"`
+	// Role of the assets used as inputs
+	Role string `yaml:"role"`
+	// Source of a generated input, such as "color=black:s=1280x720:r=25". Exclusive with Role
+	Lavfi string `yaml:"lavfi"`
+	// Options applied to each input, one argument per item, such as ["-loop", "1"]
+	Options []string `yaml:"options"`
+}
+
+// RecipeFilter A single FFmpeg filter of the recipe filtergraph
+type RecipeFilter struct {
+	// Name of the FFmpeg filter
+	Filter string `yaml:"filter"`
+	// Streams used by the filter, in order. A stream is either an input, "<input>.<index>[:<stream>]",
+	// all assets of an input, "<input>.*[:<stream>]", or an output of a previous filter
+	Inputs []string `yaml:"inputs"`
+	// Names of the filter outputs
+	Outputs []string `yaml:"outputs"`
+	// Options of the filter, in order. "${<input>.count}" in a value is replaced by the number of assets of the input
+	Options []filtergraph.RawOption `yaml:"options"`
+}
+
+// RecipeOutput Streams written into the output and their encoding
+type RecipeOutput struct {
+	// Streams of the output, in order. Same syntax as RecipeFilter.Inputs
+	Map []string `yaml:"map"`
+	// Output options, one argument per item, such as ["-c:v", "libx264"]
+	Options []string `yaml:"options"`
+}
+
+// Number of assets of a role, from min to max. A max of -1 means no limit
+type cardinality struct {
+	min, max int
+}
+
+var (
+	cardinalityRegex = regexp.MustCompile(`^(\d+)(\+|-(\d+))?$`)
+	// Names of inputs and filter outputs
+	recipeNameRegex = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9_]*$`)
+	// Reference to an input : "audio.0", "video.0:v", "audio.*"
+	inputRefRegex = regexp.MustCompile(`^([a-zA-Z][a-zA-Z0-9_]*)\.(\d+|\*)(:[a-z0-9:]+)?$`)
+	// Count placeholder in option values
+	countRegex = regexp.MustCompile(`\$\{([a-zA-Z][a-zA-Z0-9_]*)\.count\}`)
+)
+
+func parseCardinality(value string) (cardinality, error) {
+	m := cardinalityRegex.FindStringSubmatch(strings.TrimSpace(value))
+	if m == nil {
+		return cardinality{}, fmt.Errorf("invalid count \"%s\", either \"N\", \"N+\" or \"N-M\"", value)
+	}
+	min, _ := strconv.Atoi(m[1])
+	switch {
+	case m[2] == "":
+		return cardinality{min, min}, nil
+	case m[2] == "+":
+		return cardinality{min, -1}, nil
+	}
+	max, _ := strconv.Atoi(m[3])
+	if max < min {
+		return cardinality{}, fmt.Errorf("invalid count \"%s\", %d is lower than %d", value, max, min)
+	}
+	return cardinality{min, max}, nil
+}
+
+// ParseRecipe Parse and check a single recipe. JSON being valid YAML, both formats are accepted
+func ParseRecipe(data []byte) (*Recipe, error) {
+	var recipe Recipe
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&recipe); err != nil {
+		return nil, fmt.Errorf("could not parse recipe : %w", err)
+	}
+	if err := recipe.validate(); err != nil {
+		return nil, err
+	}
+	return &recipe, nil
+}
+
+// LoadRecipes Load all recipes (*.yaml, *.yml and *.json files) of dir, indexed by name
+func LoadRecipes(dir string) (map[string]*Recipe, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("could not read recipes directory : %w", err)
+	}
+	recipes := map[string]*Recipe{}
+	for _, entry := range entries {
+		ext := strings.ToLower(filepath.Ext(entry.Name()))
+		if entry.IsDir() || (ext != ".yaml" && ext != ".yml" && ext != ".json") {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("could not read recipe %s : %w", entry.Name(), err)
+		}
+		recipe, err := ParseRecipe(data)
+		if err != nil {
+			return nil, fmt.Errorf("invalid recipe %s : %w", entry.Name(), err)
+		}
+		if recipe.Name == "" {
+			recipe.Name = strings.TrimSuffix(entry.Name(), filepath.Ext(entry.Name()))
+		}
+		if _, exists := recipes[recipe.Name]; exists {
+			return nil, fmt.Errorf("recipe %s is defined multiple times", recipe.Name)
+		}
+		recipes[recipe.Name] = recipe
+	}
+	return recipes, nil
+}
+
+// Check that the recipe is consistent, regardless of the assets it will be used with
+func (r *Recipe) validate() error {
+	var errs []error
+	r.bounds = map[string]cardinality{}
+	for role, value := range r.Accepts {
+		if !slices.Contains(recipeRoles, role) {
+			errs = append(errs, fmt.Errorf("unknown role \"%s\" in accepts, must be one of %s", role, strings.Join(recipeRoles, ", ")))
+			continue
+		}
+		bounds, err := parseCardinality(value)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("role %s : %w", role, err))
+			continue
+		}
+		r.bounds[role] = bounds
+	}
+
+	// Names of the inputs and filter outputs defined so far
+	inputs := map[string]bool{}
+	pads := map[string]bool{}
+	for i, input := range r.Inputs {
+		name := input.name()
+		switch {
+		case (input.Role == "") == (input.Lavfi == ""):
+			errs = append(errs, fmt.Errorf("input %d must define either a role or a lavfi source", i))
+		case input.Role != "" && !slices.Contains(recipeRoles, input.Role):
+			errs = append(errs, fmt.Errorf("unknown role \"%s\" for input %d", input.Role, i))
+		case !recipeNameRegex.MatchString(name):
+			errs = append(errs, fmt.Errorf("invalid name \"%s\" for input %d", name, i))
+		case inputs[name]:
+			errs = append(errs, fmt.Errorf("input %s is defined multiple times", name))
+		}
+		inputs[name] = true
+	}
+	checkRef := func(ref string, where string) {
+		if m := inputRefRegex.FindStringSubmatch(ref); m != nil {
+			if !inputs[m[1]] {
+				errs = append(errs, fmt.Errorf("%s uses the unknown input %s", where, m[1]))
+			}
+		} else if !pads[ref] {
+			errs = append(errs, fmt.Errorf("%s uses \"%s\", which is neither an input nor the output of a previous filter", where, ref))
+		}
+	}
+	for i, f := range r.Filters {
+		where := fmt.Sprintf("filter %d (%s)", i, f.Filter)
+		if !filtergraph.IsValidFilterName(f.Filter) {
+			errs = append(errs, fmt.Errorf("%s : invalid filter name", where))
+		}
+		if len(f.Inputs) == 0 || len(f.Outputs) == 0 {
+			errs = append(errs, fmt.Errorf("%s : at least one input and one output are required", where))
+		}
+		for _, ref := range f.Inputs {
+			checkRef(ref, where)
+		}
+		for _, o := range f.Options {
+			if o.Key != "" && !filtergraph.IsValidOptionKey(o.Key) {
+				errs = append(errs, fmt.Errorf("%s : invalid option key \"%s\"", where, o.Key))
+			}
+			for _, m := range countRegex.FindAllStringSubmatch(o.Value, -1) {
+				if !inputs[m[1]] {
+					errs = append(errs, fmt.Errorf("%s : unknown input %s in option %s", where, m[1], o.Key))
+				}
+			}
+		}
+		for _, out := range f.Outputs {
+			if !recipeNameRegex.MatchString(out) || pads[out] {
+				errs = append(errs, fmt.Errorf("%s : invalid or duplicated output name \"%s\"", where, out))
+			}
+			pads[out] = true
+		}
+	}
+	if len(r.Output.Map) == 0 {
+		errs = append(errs, fmt.Errorf("the output must map at least one stream"))
+	}
+	for _, ref := range r.Output.Map {
+		checkRef(ref, "output")
+	}
+	return errors.Join(errs...)
+}
+
+func (ri *RecipeInput) name() string {
+	if ri.Name != "" {
+		return ri.Name
+	}
+	return ri.Role
+}
+
+// Supports Return an error if the recipe can't be used with the given assets paths, indexed by role
+func (r *Recipe) Supports(assets map[string][]string) error {
+	for _, role := range recipeRoles {
+		count := len(assets[role])
+		bounds, listed := r.bounds[role]
+		if !listed {
+			if count > 0 {
+				return fmt.Errorf("recipe %s does not accept any %s", r.Name, role)
+			}
+			continue
+		}
+		if count < bounds.min || (bounds.max >= 0 && count > bounds.max) {
+			return fmt.Errorf("recipe %s accepts %s %s, got %d", r.Name, r.Accepts[role], role, count)
+		}
+	}
+	return nil
+}
+
+// GetRecipeEnc Return an initialized encoder following the recipe, using the given assets paths indexed by role
+func GetRecipeEnc(ctx *context.Context, recipe *Recipe, assets map[string][]string, output string) (*Encoder, error) {
+	if err := recipe.Supports(assets); err != nil {
+		return nil, err
+	}
+	builder := Builder{}
+	// FFmpeg index of each input, by input name
+	indexes := map[string][]int{}
+	for _, input := range recipe.Inputs {
+		name := input.name()
+		if input.Lavfi != "" {
+			indexes[name] = []int{len(builder.inputs)}
+			builder.AddInput(&FileInput{Path: input.Lavfi, Format: "lavfi", Options: input.Options})
+			continue
+		}
+		for _, path := range assets[input.Role] {
+			indexes[name] = append(indexes[name], len(builder.inputs))
+			builder.AddInput(&FileInput{Path: path, Options: input.Options})
+		}
+	}
+
+	// Resolve a reference into the streams it designates
+	pads := map[string]filtergraph.Filter{}
+	resolve := func(ref string) ([]filtergraph.Filter, error) {
+		m := inputRefRegex.FindStringSubmatch(ref)
+		if m == nil {
+			return []filtergraph.Filter{pads[ref]}, nil
+		}
+		var streams []filtergraph.Filter
+		for i, index := range indexes[m[1]] {
+			if m[2] == "*" || m[2] == strconv.Itoa(i) {
+				streams = append(streams, filtergraph.NewInput(fmt.Sprintf("%d%s", index, m[3])))
+			}
+		}
+		if len(streams) == 0 && m[2] != "*" {
+			return nil, fmt.Errorf("%s is used, but there are only %d %s", ref, len(indexes[m[1]]), m[1])
+		}
+		return streams, nil
+	}
+	replaceCounts := func(value string) string {
+		return countRegex.ReplaceAllStringFunc(value, func(placeholder string) string {
+			return strconv.Itoa(len(indexes[countRegex.FindStringSubmatch(placeholder)[1]]))
+		})
+	}
+
+	for _, f := range recipe.Filters {
+		var inputs []filtergraph.Filter
+		for _, ref := range f.Inputs {
+			streams, err := resolve(ref)
+			if err != nil {
+				return nil, fmt.Errorf("filter %s : %w", f.Filter, err)
+			}
+			inputs = append(inputs, streams...)
+		}
+		options := make([]filtergraph.RawOption, len(f.Options))
+		for i, o := range f.Options {
+			options[i] = filtergraph.RawOption{Key: o.Key, Value: replaceCounts(o.Value)}
+		}
+		raw := filtergraph.NewRawFilter(f.Filter, options, len(f.Outputs), inputs...)
+		for i, out := range f.Outputs {
+			pads[out] = raw.Output(i)
+		}
+	}
+
+	// Only the mapped filter outputs are roots of the graph. Any other unused output is an error
+	for _, ref := range recipe.Output.Map {
+		streams, err := resolve(ref)
+		if err != nil {
+			return nil, fmt.Errorf("output : %w", err)
+		}
+		for _, stream := range streams {
+			if _, isInput := stream.(*filtergraph.InputFilter); !isInput {
+				builder.AddFilterGraph(stream)
+			}
+			builder.MapStream(stream)
+		}
+	}
+	builder.AddOutputOption(recipe.Output.Options...)
+	builder.SetOutput(output)
+	enc, err := builder.Build(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("recipe %s : %w", recipe.Name, err)
+	}
+	return enc, nil
+}
@@ -0,0 +1,134 @@
+package encoder
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"path/filepath"
+	"testing"
+)
+
+const recipesDir = "../../resources/recipes"
+
+func TestRecipe_LoadBundled(t *testing.T) {
+	recipes, err := LoadRecipes(recipesDir)
+	assert.NoError(t, err)
+	assert.Contains(t, recipes, "video-watermark")
+}
+
+func TestRecipe_Encoder(t *testing.T) {
+	recipes, err := LoadRecipes(recipesDir)
+	assert.NoError(t, err)
+	ctx := context.Background()
+	enc, err := GetRecipeEnc(&ctx, recipes["video-watermark"], map[string][]string{
+		VideoRole: {"/tmp/video.mp4"},
+		ImageRole: {"/tmp/logo.png"},
+		AudioRole: {"/tmp/a.mp3", "/tmp/b.mp3"},
+	}, "/tmp/out.mp4")
+	assert.NoError(t, err)
+	// Labels are deterministic, the whole command can be checked
+	assert.Equal(t, []string{
+		"-i", "/tmp/video.mp4", "-i", "/tmp/logo.png", "-i", "/tmp/a.mp3", "-i", "/tmp/b.mp3",
+		"-filter_complex", `[1]scale=w=\'160\':h=\'-2\'[raw3];[0:v][raw3]overlay=x=\'W-w-20\':y=\'20\'[raw4];` +
+			`[2][3]concat=n=\'2\':v=\'0\':a=\'1\'[raw7];[raw7]speechnorm[raw8]`,
+		"-map", "[raw4]", "-map", "[raw8]",
+		"-c:v", "libx264", "-preset", "medium", "-crf", "23", "-pix_fmt", "yuv420p", "-c:a", "aac", "-shortest",
+		"/tmp/out.mp4",
+	}, enc.args)
+}
+
+// Asset paths are only ever used as input arguments, never inside the filtergraph
+func FuzzRecipe_AssetKeys(f *testing.F) {
+	recipes, err := LoadRecipes(recipesDir)
+	assert.NoError(f, err)
+	f.Add("video.mp4", "a b.mp3")
+	f.Add("[0:v]overlay[out];.png", "it's.mp3")
+	f.Add("-map", "-filter_complex")
+	f.Fuzz(func(t *testing.T, videoKey, audioKey string) {
+		video, audio := filepath.Join("/tmp/assets", videoKey), filepath.Join("/tmp/assets", audioKey)
+		ctx := context.Background()
+		enc, err := GetRecipeEnc(&ctx, recipes["video-watermark"], map[string][]string{
+			VideoRole: {video},
+			ImageRole: {"/tmp/logo.png"},
+			AudioRole: {audio},
+		}, "/tmp/out.mp4")
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"-i", video, "-i", "/tmp/logo.png", "-i", audio, "-filter_complex"}, enc.args[:7])
+		assert.Equal(t, `[1]scale=w=\'160\':h=\'-2\'[raw3];[0:v][raw3]overlay=x=\'W-w-20\':y=\'20\'[raw4];`+
+			`[2]concat=n=\'1\':v=\'0\':a=\'1\'[raw6];[raw6]speechnorm[raw7]`, enc.args[7])
+	})
+}
+
+func TestRecipe_Supports(t *testing.T) {
+	recipe, err := ParseRecipe([]byte(`
+accepts: { audio: "1-2", image: "0+" }
+inputs: [{ role: audio }]
+output: { map: [audio.0] }
+`))
+	assert.NoError(t, err)
+	assert.NoError(t, recipe.Supports(map[string][]string{AudioRole: {"a"}}))
+	assert.NoError(t, recipe.Supports(map[string][]string{AudioRole: {"a", "b"}, ImageRole: {"i"}}))
+	assert.Error(t, recipe.Supports(map[string][]string{}))
+	assert.Error(t, recipe.Supports(map[string][]string{AudioRole: {"a", "b", "c"}}))
+	// Roles not listed are refused
+	assert.Error(t, recipe.Supports(map[string][]string{AudioRole: {"a"}, VideoRole: {"v"}}))
+}
+
+func TestRecipe_JSON(t *testing.T) {
+	recipe, err := ParseRecipe([]byte(`{
+		"name": "echo",
+		"accepts": {"audio": "1"},
+		"inputs": [{"role": "audio"}, {"name": "bg", "lavfi": "color=black:s=640x360:r=1"}],
+		"filters": [{"filter": "aecho", "inputs": ["audio.0"], "outputs": ["echo"], "options": [{"value": "0.8"}, {"value": "0.9"}]}],
+		"output": {"map": ["bg.0", "echo"], "options": ["-t", "60"]}
+	}`))
+	assert.NoError(t, err)
+	ctx := context.Background()
+	enc, err := GetRecipeEnc(&ctx, recipe, map[string][]string{AudioRole: {"/tmp/a.mp3"}}, "/tmp/out.mp4")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		"-i", "/tmp/a.mp3", "-f", "lavfi", "-i", "color=black:s=640x360:r=1",
+		"-filter_complex", `[0]aecho=\'0.8\':\'0.9\'[raw2]`, "-map", "1", "-map", "[raw2]", "-t", "60", "/tmp/out.mp4",
+	}, enc.args)
+}
+
+func TestRecipe_Invalid(t *testing.T) {
+	for _, recipe := range []string{
+		// Unknown field
+		`{ output: { map: [a.0] }, unknown: 1 }`,
+		// Unknown role
+		`{ accepts: { podcast: "1" }, inputs: [{ role: audio }], output: { map: [audio.0] } }`,
+		`{ inputs: [{ role: podcast }], output: { map: [podcast.0] } }`,
+		// Invalid count
+		`{ accepts: { audio: "2-1" }, inputs: [{ role: audio }], output: { map: [audio.0] } }`,
+		// Both a role and a lavfi source
+		`{ inputs: [{ role: audio, lavfi: "sine" }], output: { map: [audio.0] } }`,
+		// Unknown stream, or filter output used before being defined
+		`{ inputs: [{ role: audio }], output: { map: [video.0] } }`,
+		`{ inputs: [{ role: audio }], filters: [{ filter: anull, inputs: [later], outputs: [out] }, { filter: anull, inputs: [audio.0], outputs: [later] }], output: { map: [out] } }`,
+		// Invalid filter name or option key
+		`{ inputs: [{ role: audio }], filters: [{ filter: "anull,movie", inputs: [audio.0], outputs: [out] }], output: { map: [out] } }`,
+		`{ inputs: [{ role: audio }], filters: [{ filter: volume, inputs: [audio.0], outputs: [out], options: [{ key: "a=b", value: "1" }] }], output: { map: [out] } }`,
+		// Nothing in the output
+		`{ inputs: [{ role: audio }] }`,
+	} {
+		_, err := ParseRecipe([]byte(recipe))
+		assert.Error(t, err, recipe)
+	}
+}
+
+func TestRecipe_InvalidGraph(t *testing.T) {
+	ctx := context.Background()
+	// The second output of the split is never used
+	recipe, err := ParseRecipe([]byte(`{
+		accepts: { audio: "1" }, inputs: [{ role: audio }],
+		filters: [{ filter: asplit, inputs: [audio.0], outputs: [a, b] }],
+		output: { map: [a] } }`))
+	assert.NoError(t, err)
+	_, err = GetRecipeEnc(&ctx, recipe, map[string][]string{AudioRole: {"/tmp/a.mp3"}}, "/tmp/out.mp4")
+	assert.ErrorContains(t, err, "never used")
+	// A stream that doesn't exist with the given assets
+	recipe, err = ParseRecipe([]byte(`{ accepts: { audio: "1+" }, inputs: [{ role: audio }], output: { map: [audio.1] } }`))
+	assert.NoError(t, err)
+	_, err = GetRecipeEnc(&ctx, recipe, map[string][]string{AudioRole: {"/tmp/a.mp3"}}, "/tmp/out.mp4")
+	assert.ErrorContains(t, err, "audio.1")
+}
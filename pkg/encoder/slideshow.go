@@ -0,0 +1,100 @@
+package encoder
+
+import (
+	"encode-box/pkg/encoder/filtergraph"
+	"fmt"
+	"time"
+)
+
+// SlideshowOptions How the images of a slideshow are displayed
+type SlideshowOptions struct {
+	// Display duration of each image but the last one, which is displayed until the end of the audio.
+	// If empty, the audio duration is evenly split between all images
+	Durations []time.Duration
+	// xfade transition effect between two images ("fade", "wipeleft"...). No transition if empty
+	Transition string
+	// Duration of each transition. Default to 1s
+	TransitionDuration time.Duration
+	// Slowly zoom on each image (Ken Burns effect)
+	KenBurns bool
+}
+
+// Return the duration of each transition, 0 if there are no transitions
+func (so *SlideshowOptions) transitionDuration() time.Duration {
+	if so.Transition == "" {
+		return 0
+	}
+	if so.TransitionDuration == 0 {
+		return time.Second
+	}
+	return so.TransitionDuration
+}
+
+// Compute the display duration of each of the count images, so that the slideshow lasts total
+func slideDurations(count int, total time.Duration, so *SlideshowOptions) ([]time.Duration, error) {
+	durations := make([]time.Duration, count)
+	if len(so.Durations) == 0 {
+		for i := range durations {
+			durations[i] = total / time.Duration(count)
+		}
+	} else {
+		if len(so.Durations) != count && len(so.Durations) != count-1 {
+			return nil, fmt.Errorf("%d durations given for %d images", len(so.Durations), count)
+		}
+		var elapsed time.Duration
+		for i := 0; i < count-1; i++ {
+			durations[i] = so.Durations[i]
+			elapsed += durations[i]
+		}
+		// The last image lasts until the end of the audio
+		durations[count-1] = total - elapsed
+	}
+	// A transition can't start before the previous one ends
+	for i, d := range durations {
+		if d <= so.transitionDuration() {
+			return nil, fmt.Errorf("image %d would be displayed for %s, which is too short", i, d)
+		}
+	}
+	return durations, nil
+}
+
+// Add all images as inputs of the builder, and return the resulting slideshow video, fitting in the canvas
+func addSlideshow(builder *Builder, imagePaths []string, durations []time.Duration, opt *PresetOptions) filtergraph.Filter {
+	canvas := opt.canvas()
+	transition := opt.Slideshow.transitionDuration()
+	var slides []filtergraph.Filter
+	for i, imagePath := range imagePaths {
+		// Each image but the last one must also last during the transition to the next one
+		duration := durations[i]
+		if i < len(imagePaths)-1 {
+			duration += transition
+		}
+		index := len(builder.inputs)
+		var slide filtergraph.Filter = filtergraph.NewInput(fmt.Sprintf("%d:v", index))
+		if opt.Slideshow.KenBurns {
+			// The zoom generates all frames from a single image
+			builder.AddInput(&FileInput{Path: imagePath})
+			slide = filtergraph.NewVideoNormalizeFilter(slide, canvas)
+			slide = filtergraph.NewKenBurnsFilter(slide, canvas, duration)
+		} else {
+			builder.AddInput(&FileInput{Path: imagePath, Options: []string{"-loop", "1", "-t", fmt.Sprintf("%.3f", duration.Seconds())}})
+			slide = filtergraph.NewVideoNormalizeFilter(slide, canvas)
+		}
+		slides = append(slides, slide)
+	}
+
+	if len(slides) == 1 {
+		return slides[0]
+	}
+	if transition == 0 {
+		return filtergraph.NewVideoConcatFilter(slides...)
+	}
+	// Each transition starts when the previous image should end
+	videoRoot := slides[0]
+	var offset time.Duration
+	for i, slide := range slides[1:] {
+		offset += durations[i]
+		videoRoot = filtergraph.NewXfadeFilter(videoRoot, slide, opt.Slideshow.Transition, transition, offset)
+	}
+	return videoRoot
+}
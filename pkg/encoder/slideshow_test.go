@@ -0,0 +1,63 @@
+package encoder
+
+import (
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSlideshow_Durations_Even(t *testing.T) {
+	durations, err := slideDurations(3, 30*time.Second, &SlideshowOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, []time.Duration{10 * time.Second, 10 * time.Second, 10 * time.Second}, durations)
+}
+
+func TestSlideshow_Durations_LastUntilEnd(t *testing.T) {
+	so := &SlideshowOptions{Durations: []time.Duration{5 * time.Second, 10 * time.Second}}
+	durations, err := slideDurations(3, 30*time.Second, so)
+	assert.NoError(t, err)
+	assert.Equal(t, []time.Duration{5 * time.Second, 10 * time.Second, 15 * time.Second}, durations)
+	// The duration of the last image is ignored
+	so.Durations = append(so.Durations, time.Second)
+	durations, err = slideDurations(3, 30*time.Second, so)
+	assert.NoError(t, err)
+	assert.Equal(t, 15*time.Second, durations[2])
+}
+
+func TestSlideshow_Durations_Invalid(t *testing.T) {
+	// Wrong number of durations
+	_, err := slideDurations(3, 30*time.Second, &SlideshowOptions{Durations: []time.Duration{time.Second}})
+	assert.Error(t, err)
+	// Longer than the audio
+	_, err = slideDurations(2, 30*time.Second, &SlideshowOptions{Durations: []time.Duration{40 * time.Second}})
+	assert.Error(t, err)
+	// Shorter than a transition
+	_, err = slideDurations(2, 30*time.Second, &SlideshowOptions{Durations: []time.Duration{time.Second}, Transition: "fade", TransitionDuration: 2 * time.Second})
+	assert.Error(t, err)
+}
+
+func TestSlideshow_Xfade(t *testing.T) {
+	builder := Builder{}
+	opt := &PresetOptions{Slideshow: SlideshowOptions{Transition: "fade"}}
+	videoRoot := addSlideshow(&builder, []string{"a.jpg", "b.jpg", "c.jpg"}, []time.Duration{5 * time.Second, 10 * time.Second, 15 * time.Second}, opt)
+	// Each image but the last one also lasts during the transition
+	assert.Equal(t, []string{"-loop", "1", "-t", "6.000"}, builder.inputs[0].Options)
+	assert.Equal(t, []string{"-loop", "1", "-t", "11.000"}, builder.inputs[1].Options)
+	assert.Equal(t, []string{"-loop", "1", "-t", "15.000"}, builder.inputs[2].Options)
+	graph := videoRoot.Build()
+	assert.Contains(t, graph, "xfade=transition=fade:duration=1:offset=5[")
+	assert.Contains(t, graph, "xfade=transition=fade:duration=1:offset=15[")
+}
+
+func TestSlideshow_KenBurnsConcat(t *testing.T) {
+	builder := Builder{}
+	opt := &PresetOptions{Slideshow: SlideshowOptions{KenBurns: true}}
+	videoRoot := addSlideshow(&builder, []string{"a.jpg", "b.jpg"}, []time.Duration{2 * time.Second, 4 * time.Second}, opt)
+	assert.Empty(t, builder.inputs[0].Options)
+	graph := videoRoot.Build()
+	assert.Equal(t, 2, strings.Count(graph, "zoompan="))
+	assert.Contains(t, graph, ":d=50:")
+	assert.Contains(t, graph, ":d=100:")
+	assert.Contains(t, graph, "concat=n=2:v=1:a=0")
+}
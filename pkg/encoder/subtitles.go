@@ -0,0 +1,113 @@
+package encoder
+
+import (
+	"encode-box/pkg/encoder/filtergraph"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SubtitleOptions Subtitles to add to the output
+type SubtitleOptions struct {
+	// Path of the subtitles file (SRT or WebVTT)
+	Path string
+	// If true, subtitles are burnt into the video. Otherwise, they are added as a soft subtitle track
+	Burn bool
+	// ISO 639-2 language of a soft subtitle track (eng, fra...)
+	Language string
+	// Appearance of burnt subtitles
+	Style filtergraph.SubtitleStyle
+}
+
+// Add the subtitles as a soft subtitle track of the output, delayed by offset
+func addSoftSubtitles(builder *Builder, subs *SubtitleOptions, offset time.Duration) {
+	if subs == nil || subs.Burn {
+		return
+	}
+	index := len(builder.inputs)
+	input := &FileInput{Path: subs.Path}
+	if offset > 0 {
+		input.Options = append(input.Options, "-itsoffset", fmt.Sprintf("%.3f", offset.Seconds()))
+	}
+	builder.AddInput(input)
+	builder.
+		AddOutputOption("-map", fmt.Sprintf("%d:s", index)).
+		AddOutputOption("-c:s", subtitleCodec(builder.output))
+	if subs.Language != "" {
+		builder.AddOutputOption("-metadata:s:s:0", fmt.Sprintf("language=%s", subs.Language))
+	}
+}
+
+// Return the subtitle codec supported by the output container
+func subtitleCodec(output string) string {
+	switch strings.ToLower(filepath.Ext(output)) {
+	case ".webm":
+		return "webvtt"
+	case ".mkv":
+		return "srt"
+	default:
+		// MP4 and MOV only support their own timed text format
+		return "mov_text"
+	}
+}
+
+// Return opt with subtitles following the output timeline, once opt.Cuts are removed. The shifted
+// subtitles are written next to the output
+func withCutSubtitles(opt *PresetOptions, output string) (*PresetOptions, error) {
+	if opt.Subtitles == nil || len(opt.Cuts) == 0 {
+		return opt, nil
+	}
+	path := output + filepath.Ext(opt.Subtitles.Path)
+	if err := shiftSubtitles(opt.Subtitles.Path, path, opt.Cuts); err != nil {
+		return nil, fmt.Errorf("could not shift subtitles : %w", err)
+	}
+	subs := *opt.Subtitles
+	subs.Path = path
+	shifted := *opt
+	shifted.Subtitles = &subs
+	return &shifted, nil
+}
+
+// SRT (00:01:02,500) and WebVTT (00:01:02.500 or 01:02.500) cue timestamps
+var cueTimestampRegex = regexp.MustCompile(`(?:(\d+):)?(\d{2}):(\d{2})([,.])(\d{3})`)
+
+// Write the subtitles of src into dst, with each cue timestamp shifted the same way as chapters.
+// A cue entirely in a removed part ends up empty, and is never displayed
+func shiftSubtitles(src string, dst string, cuts []filtergraph.TimeRange) error {
+	content, err := os.ReadFile(src)
+	if err != nil {
+		return err
+	}
+	lines := strings.Split(string(content), "\n")
+	for i, line := range lines {
+		// Only cue timings lines hold timestamps, the text itself must be left untouched
+		if !strings.Contains(line, "-->") {
+			continue
+		}
+		lines[i] = cueTimestampRegex.ReplaceAllStringFunc(line, func(ts string) string {
+			m := cueTimestampRegex.FindStringSubmatch(ts)
+			hours, _ := strconv.Atoi(m[1])
+			minutes, _ := strconv.Atoi(m[2])
+			seconds, _ := strconv.Atoi(m[3])
+			millis, _ := strconv.Atoi(m[5])
+			t := time.Duration(hours)*time.Hour + time.Duration(minutes)*time.Minute +
+				time.Duration(seconds)*time.Second + time.Duration(millis)*time.Millisecond
+			return formatCueTimestamp(shiftForCuts(t, cuts), m[4])
+		})
+	}
+	return os.WriteFile(dst, []byte(strings.Join(lines, "\n")), 0600)
+}
+
+// Format t as a cue timestamp, using separator between the seconds and the milliseconds
+func formatCueTimestamp(t time.Duration, separator string) string {
+	return fmt.Sprintf("%02d:%02d:%02d%s%03d",
+		int(t.Hours()),
+		int(t.Minutes())%60,
+		int(t.Seconds())%60,
+		separator,
+		t.Milliseconds()%1000)
+}
@@ -0,0 +1,87 @@
+package encoder
+
+import (
+	"encode-box/pkg/encoder/filtergraph"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSubtitles_Codec(t *testing.T) {
+	assert.Equal(t, "mov_text", subtitleCodec("/tmp/out.mp4"))
+	assert.Equal(t, "webvtt", subtitleCodec("/tmp/out.webm"))
+	assert.Equal(t, "srt", subtitleCodec("/tmp/out.MKV"))
+}
+
+func TestSubtitles_Soft(t *testing.T) {
+	builder := Builder{}
+	builder.AddInput(&FileInput{Path: "/tmp/video.mp4"}).SetOutput("/tmp/out.mp4")
+	addSoftSubtitles(&builder, &SubtitleOptions{Path: "/tmp/sub.srt", Language: "fra"}, 0)
+	cmd := builderCommandLine(t, &builder)
+	assert.Contains(t, cmd, "-i /tmp/sub.srt")
+	assert.Contains(t, cmd, "-map 1:s -c:s mov_text -metadata:s:s:0 language=fra")
+}
+
+func TestSubtitles_BurnAddsNoTrack(t *testing.T) {
+	builder := Builder{}
+	builder.AddInput(&FileInput{Path: "/tmp/video.mp4"}).SetOutput("/tmp/out.mp4")
+	addSoftSubtitles(&builder, &SubtitleOptions{Path: "/tmp/sub.srt", Burn: true}, 0)
+	cmd := builderCommandLine(t, &builder)
+	assert.False(t, strings.Contains(cmd, "sub.srt"))
+}
+
+func TestSubtitles_SoftWithOffset(t *testing.T) {
+	builder := Builder{}
+	builder.AddInput(&FileInput{Path: "/tmp/video.mp4"}).SetOutput("/tmp/out.mkv")
+	addSoftSubtitles(&builder, &SubtitleOptions{Path: "/tmp/sub.srt"}, 2500*time.Millisecond)
+	cmd := builderCommandLine(t, &builder)
+	assert.Contains(t, cmd, "-itsoffset 2.500 -i /tmp/sub.srt")
+}
+
+// Cues are shifted like chapters, only timings lines are modified
+func TestSubtitles_Shift(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "sub.srt")
+	err := os.WriteFile(src, []byte("1\n00:00:01,000 --> 00:00:03,000\nAt 00:00:02,000\n\n"+
+		"2\n00:00:05,000 --> 00:00:06,500\nInside the cut\n\n"+
+		"3\n00:01:10,250 --> 00:01:12,000\nAfter\n"), 0600)
+	assert.Nil(t, err)
+	opt, err := withCutSubtitles(&PresetOptions{
+		Subtitles: &SubtitleOptions{Path: src},
+		Cuts:      []filtergraph.TimeRange{{Start: 4 * time.Second, End: 7 * time.Second}},
+	}, filepath.Join(dir, "out.mp4"))
+	assert.Nil(t, err)
+	assert.Equal(t, filepath.Join(dir, "out.mp4.srt"), opt.Subtitles.Path)
+	shifted, err := os.ReadFile(opt.Subtitles.Path)
+	assert.Nil(t, err)
+	assert.Equal(t, "1\n00:00:01,000 --> 00:00:03,000\nAt 00:00:02,000\n\n"+
+		"2\n00:00:04,000 --> 00:00:04,000\nInside the cut\n\n"+
+		"3\n00:01:07,250 --> 00:01:09,000\nAfter\n", string(shifted))
+	// The original subtitles are left untouched
+	original, err := os.ReadFile(src)
+	assert.Nil(t, err)
+	assert.Contains(t, string(original), "00:01:10,250 --> 00:01:12,000")
+}
+
+func TestSubtitles_ShiftVTT(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "sub.vtt")
+	err := os.WriteFile(src, []byte("WEBVTT\n\n00:05.000 --> 00:08.000 line:0\nHello\n"), 0600)
+	assert.Nil(t, err)
+	dst := filepath.Join(dir, "shifted.vtt")
+	assert.Nil(t, shiftSubtitles(src, dst, []filtergraph.TimeRange{{Start: 0, End: 2 * time.Second}}))
+	shifted, err := os.ReadFile(dst)
+	assert.Nil(t, err)
+	assert.Equal(t, "WEBVTT\n\n00:00:03.000 --> 00:00:06.000 line:0\nHello\n", string(shifted))
+}
+
+// Without cuts, the subtitles are used as is
+func TestSubtitles_NoCuts(t *testing.T) {
+	opt := &PresetOptions{Subtitles: &SubtitleOptions{Path: "/tmp/sub.srt"}}
+	shifted, err := withCutSubtitles(opt, "/tmp/out.mp4")
+	assert.Nil(t, err)
+	assert.Same(t, opt, shifted)
+}
@@ -0,0 +1,153 @@
+package encoder
+
+import (
+	"context"
+	"encode-box/pkg/encoder/filtergraph"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// Number of frames the poster is chosen from
+const posterCandidates = 100
+
+// ExtractPoster Write a single frame of videoPath as an image at output.
+// If at is nil, the most representative frame among frames sampled over the whole video is chosen
+func ExtractPoster(ctx *context.Context, videoPath string, output string, at *time.Duration) error {
+	builder := Builder{}
+	if at != nil {
+		// Seeking before the input is way faster than decoding the video up to the frame
+		builder.AddInput(&FileInput{Path: videoPath, Options: []string{"-ss", fmt.Sprintf("%.3f", at.Seconds())}})
+		builder.AddOutputOption("-map", "0:v")
+	} else {
+		duration, err := GetDuration(videoPath)
+		if err != nil {
+			return fmt.Errorf("could not get video duration : %w", err)
+		}
+		builder.AddInput(&FileInput{Path: videoPath})
+		thumbnail := filtergraph.NewThumbnailFilter(filtergraph.NewInput("0:v"), sampling(duration, posterCandidates), posterCandidates)
+		builder.SetFilterGraph(thumbnail).MapStream(thumbnail)
+	}
+	builder.
+		AddOutputOption("-frames:v", "1").
+		AddOutputOption("-y").
+		SetOutput(output)
+	if out, err := runFFmpeg(ctx, &builder); err != nil {
+		return fmt.Errorf("could not extract poster : %w, %s", err, lastLine(out))
+	}
+	return nil
+}
+
+// SpriteOptions Thumbnails of a sprite sheet
+type SpriteOptions struct {
+	// Time between two thumbnails. Default to 10s
+	Interval time.Duration
+	// Size of a single thumbnail. Default to 160x90
+	Width  int
+	Height int
+	// Number of thumbnails in each row of the sheet. Default to 10
+	Columns int
+}
+
+// Return the options with defaults values for all unset fields
+func (so SpriteOptions) withDefaults() SpriteOptions {
+	if so.Interval == 0 {
+		so.Interval = 10 * time.Second
+	}
+	if so.Width == 0 {
+		so.Width = 160
+	}
+	if so.Height == 0 {
+		so.Height = 90
+	}
+	if so.Columns == 0 {
+		so.Columns = 10
+	}
+	return so
+}
+
+// Return the layout of a sprite sheet holding thumbnails of a whole video of the given duration
+func (so SpriteOptions) layout(duration time.Duration) filtergraph.SpriteLayout {
+	count := int((duration + so.Interval - 1) / so.Interval)
+	if count < 1 {
+		count = 1
+	}
+	columns := so.Columns
+	if count < columns {
+		columns = count
+	}
+	return filtergraph.SpriteLayout{
+		Interval: so.Interval,
+		Width:    so.Width,
+		Height:   so.Height,
+		Columns:  columns,
+		Rows:     (count + columns - 1) / columns,
+	}
+}
+
+// GenerateSprite Write a sprite sheet of thumbnails of videoPath at spritePath, and its WebVTT index at indexPath.
+// The index references the sheet as spriteURL
+func GenerateSprite(ctx *context.Context, videoPath string, spritePath string, indexPath string, spriteURL string, opt SpriteOptions) error {
+	opt = opt.withDefaults()
+	duration, err := GetDuration(videoPath)
+	if err != nil {
+		return fmt.Errorf("could not get video duration : %w", err)
+	}
+	builder := Builder{}
+	builder.AddInput(&FileInput{Path: videoPath})
+	sprite := filtergraph.NewSpriteFilter(filtergraph.NewInput("0:v"), opt.layout(duration))
+	builder.
+		SetFilterGraph(sprite).
+		MapStream(sprite).
+		AddOutputOption("-frames:v", "1").
+		AddOutputOption("-y").
+		SetOutput(spritePath)
+	if out, err := runFFmpeg(ctx, &builder); err != nil {
+		return fmt.Errorf("could not generate sprite : %w, %s", err, lastLine(out))
+	}
+	return WriteSpriteIndex(indexPath, spriteURL, duration, opt)
+}
+
+// WriteSpriteIndex Write the WebVTT index of a sprite sheet, mapping each time range of the video
+// to its thumbnail in the sheet
+func WriteSpriteIndex(path string, spriteURL string, duration time.Duration, opt SpriteOptions) error {
+	opt = opt.withDefaults()
+	layout := opt.layout(duration)
+	ss := strings.Builder{}
+	ss.WriteString("WEBVTT\n")
+	for i := 0; time.Duration(i)*opt.Interval < duration; i++ {
+		start := time.Duration(i) * opt.Interval
+		end := start + opt.Interval
+		if end > duration {
+			end = duration
+		}
+		x := (i % layout.Columns) * opt.Width
+		y := (i / layout.Columns) * opt.Height
+		ss.WriteString(fmt.Sprintf("\n%s --> %s\n%s#xywh=%d,%d,%d,%d\n",
+			vttTimestamp(start), vttTimestamp(end), spriteURL, x, y, opt.Width, opt.Height))
+	}
+	return os.WriteFile(path, []byte(ss.String()), 0600)
+}
+
+// Format a duration as a WebVTT timestamp (hh:mm:ss.ttt)
+func vttTimestamp(d time.Duration) string {
+	ms := d.Milliseconds()
+	return fmt.Sprintf("%02d:%02d:%02d.%03d", ms/3600000, ms/60000%60, ms/1000%60, ms%1000)
+}
+
+// Interval at which count frames are evenly sampled over duration
+func sampling(duration time.Duration, count int) time.Duration {
+	interval := duration / time.Duration(count)
+	// Too short videos are sampled at least every 40ms (25 fps)
+	if interval < 40*time.Millisecond {
+		return 40 * time.Millisecond
+	}
+	return interval
+}
+
+// Return the last non-empty line of an FFmpeg output, usually holding the error
+func lastLine(output string) string {
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	return strings.TrimSpace(lines[len(lines)-1])
+}
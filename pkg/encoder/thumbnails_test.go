@@ -0,0 +1,65 @@
+package encoder
+
+import (
+	"context"
+	"encode-box/pkg/encoder/filtergraph"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestThumbnails_SpriteLayout(t *testing.T) {
+	opt := SpriteOptions{}.withDefaults()
+	// 25 thumbnails, on 3 rows
+	assert.Equal(t,
+		filtergraph.SpriteLayout{Interval: 10 * time.Second, Width: 160, Height: 90, Columns: 10, Rows: 3},
+		opt.layout(245*time.Second))
+	// A short video should not produce an almost empty sheet
+	assert.Equal(t, 3, opt.layout(25*time.Second).Columns)
+	assert.Equal(t, 1, opt.layout(25*time.Second).Rows)
+}
+
+func TestThumbnails_WriteSpriteIndex(t *testing.T) {
+	index := filepath.Join(t.TempDir(), "sprite.vtt")
+	err := WriteSpriteIndex(index, "job.sprite.jpg", 25500*time.Millisecond, SpriteOptions{Columns: 2})
+	assert.NoError(t, err)
+	content, err := os.ReadFile(index)
+	assert.NoError(t, err)
+	assert.Equal(t, `WEBVTT
+
+00:00:00.000 --> 00:00:10.000
+job.sprite.jpg#xywh=0,0,160,90
+
+00:00:10.000 --> 00:00:20.000
+job.sprite.jpg#xywh=160,0,160,90
+
+00:00:20.000 --> 00:00:25.500
+job.sprite.jpg#xywh=0,90,160,90
+`, string(content))
+}
+
+func TestThumbnails_VttTimestamp(t *testing.T) {
+	assert.Equal(t, "01:02:03.450", vttTimestamp(time.Hour+2*time.Minute+3450*time.Millisecond))
+}
+
+// Testing the extraction of a poster, at a given time and automatically
+func TestThumbnails_ExtractPoster(t *testing.T) {
+	dir := t.TempDir()
+	ctx := context.Background()
+	at := 2 * time.Second
+	err := ExtractPoster(&ctx, TestVideo, path.Join(dir, "at.jpg"), &at)
+	assert.Nil(t, err)
+	err = ExtractPoster(&ctx, TestVideo, path.Join(dir, "auto.jpg"), nil)
+	assert.Nil(t, err)
+}
+
+// Testing the generation of a sprite sheet
+func TestThumbnails_GenerateSprite(t *testing.T) {
+	dir := t.TempDir()
+	ctx := context.Background()
+	err := GenerateSprite(&ctx, TestVideo, path.Join(dir, "sprite.jpg"), path.Join(dir, "sprite.vtt"), "sprite.jpg", SpriteOptions{Interval: time.Second})
+	assert.Nil(t, err)
+}